@@ -0,0 +1,86 @@
+import queue
+import threading
+from concurrent.futures import Future
+from dataclasses import dataclass
+from pathlib import Path
+
+import cplex
+from algorithm.gomory import Gomory
+
+# API "anytime": il solve gira in background e ogni soluzione migliorante viene pubblicata appena trovata
+
+
+@dataclass
+class Incumbent:
+    """Soluzione intera migliorante: obiettivo, valori (nome -> valore), origine e istante (ms dall'inizio)."""
+    objective: float
+    values: dict
+    source: str
+    time_ms: float
+
+
+class SolveStream:
+    """
+    Solve in un thread separato. Gli incumbent arrivano su `incumbents` (queue.Queue, None segna la fine);
+    `result` è un Future con le statistiche del solve (o l'eccezione che lo ha interrotto).
+    cancel() (o abort() sull'aborter passato, condivisibile tra più stream) ferma il solve:
+    il branch and bound restituisce la miglior soluzione trovata e il ciclo dei tagli si ferma. Uso:
+
+        stream = solve_stream(Gomory(model), path)
+        for incumbent in stream:
+            print(incumbent.objective, incumbent.source)
+            if good_enough(incumbent):
+                stream.cancel()
+        stats = stream.result.result()
+    """
+    _END = None
+
+    def __init__(self, gomory: Gomory, instance_path, cut_mode=None, aborter: cplex.Aborter = None):
+        self.gomory = gomory
+        self.incumbents = queue.Queue()
+        self.result = Future()
+        self.aborter = aborter if aborter is not None else cplex.Aborter()
+        self._thread = threading.Thread(target=self._run, args=(str(instance_path), cut_mode),
+                                        name=f"solve-{Path(instance_path).stem}", daemon=True)
+
+    def start(self):
+        self.result.set_running_or_notify_cancel()
+        self._thread.start()
+        return self
+
+    def cancel(self):
+        """Chiede l'interruzione del solve; gli incumbent già trovati restano nella coda."""
+        self.aborter.abort()
+
+    def _publish(self, **data):
+        self.incumbents.put(Incumbent(**data))
+
+    def _run(self, instance_path, cut_mode):
+        # Listener e aborter restano collegati al Gomory solo per la durata di questo solve
+        self.gomory.add_incumbent_listener(self._publish)
+        self.gomory.aborter = self.aborter
+        try:
+            self.result.set_result(self.gomory.solve_problem(instance_path, cut_mode))
+        except BaseException as e:
+            self.result.set_exception(e)
+        finally:
+            self.gomory.remove_incumbent_listener(self._publish)
+            self.gomory.aborter = self.gomory.solver.aborter = None
+            self.incumbents.put(self._END)
+
+    def __iter__(self):
+        """Restituisce gli incumbent nell'ordine in cui vengono trovati, fino alla fine del solve."""
+        while True:
+            incumbent = self.incumbents.get()
+            if incumbent is self._END:
+                return
+            yield incumbent
+
+    def join(self, timeout=None):
+        self._thread.join(timeout)
+        return not self._thread.is_alive()
+
+
+def solve_stream(gomory: Gomory, instance_path, cut_mode=None, aborter: cplex.Aborter = None):
+    """Avvia il solve in background e restituisce lo SolveStream con gli incumbent e il risultato."""
+    return SolveStream(gomory, instance_path, cut_mode, aborter).start()
@@ -0,0 +1,57 @@
+from dataclasses import dataclass
+
+import cplex
+
+# regole di branching e di selezione dei nodi dell'ILP di riferimento, tradotte nei parametri di CPLEX
+
+# - 'auto': scelta di CPLEX;
+# - 'strong': strong branching completo, su tutte le variabili frazionarie;
+# - 'strong_restricted': strong branching su una lista di strong_candidates candidati;
+# - 'reliability': pseudocosti, inizializzati con strong branching (stessi limiti) finché non sono affidabili;
+# - 'pseudo_reduced': pseudocosti ridotti, stima economica senza strong branching;
+# - 'most_infeasible': variabile più lontana da un valore intero.
+BRANCHING_RULES = ('auto', 'strong', 'strong_restricted', 'reliability', 'pseudo_reduced',
+                   'most_infeasible')
+
+_VARIABLE_SELECT = {'auto': 0, 'strong': 3, 'strong_restricted': 3, 'reliability': 2, 'pseudo_reduced': 4,
+                    'most_infeasible': 1}
+
+# Selezione del prossimo nodo da esplorare:
+# - 'auto': default di CPLEX;
+# - 'best_bound': sempre il nodo con il bound migliore (nessun plunging);
+# - 'best_estimate' / 'best_estimate_alt': nodo con la miglior stima dell'ottimo intero, calcolata dai pseudocosti;
+# - 'depth_first': sempre un figlio del nodo corrente;
+# - 'hybrid': plunging in profondità, ritorno al nodo best-bound quando il bound del ramo corrente
+#   peggiora oltre la tolleranza node_backtrack (valori più alti = plunging più lungo).
+NODE_SELECTIONS = ('auto', 'best_bound', 'best_estimate', 'best_estimate_alt', 'depth_first', 'hybrid')
+
+_NODE_SELECT = {'best_bound': 1, 'best_estimate': 2, 'best_estimate_alt': 3, 'depth_first': 0, 'hybrid': 1}
+
+
+@dataclass
+class BranchingStrategy:
+    """
+    Regola di selezione della variabile di branching, limiti dello strong branching e selezione dei nodi.
+    strong_candidates: dimensione della lista di candidati (ignorata da 'strong', che li valuta tutti);
+    strong_iterations: iterazioni del simplesso duale per ciascun candidato (0 = automatico).
+    CPLEX conserva i bound LP calcolati per ogni candidato e li riusa nei nodi successivi.
+    node_backtrack: tolleranza di backtracking della selezione 'hybrid'.
+    """
+    rule: str = 'auto'
+    strong_candidates: int = 10
+    strong_iterations: int = 0
+    node_selection: str = 'auto'
+    node_backtrack: float = 0.9999
+
+    def apply(self, mkp: cplex.Cplex):
+        strategy = mkp.parameters.mip.strategy
+        strategy.variableselect.set(_VARIABLE_SELECT[self.rule])
+        if self.rule in ('strong', 'strong_restricted', 'reliability'):
+            candidates = mkp.variables.get_num() if self.rule == 'strong' else self.strong_candidates
+            mkp.parameters.mip.limits.strongcand.set(candidates)
+            mkp.parameters.mip.limits.strongit.set(self.strong_iterations)
+
+        if self.node_selection != 'auto':
+            strategy.nodeselect.set(_NODE_SELECT[self.node_selection])
+            # best_bound puro: si torna subito al nodo migliore, senza plunging
+            strategy.backtrack.set(0.0 if self.node_selection == 'best_bound' else self.node_backtrack)
@@ -0,0 +1,117 @@
+import math
+
+import cplex
+
+
+class CutPool:
+    """
+    Pool dei tagli aggiunti al rilassamento LP.
+    Scarta i duplicati (tramite hash dei coefficienti normalizzati), filtra i tagli
+    per efficacia e rimuove quelli che non sono più attivi da troppe iterazioni.
+    Un taglio rimosso non può essere riaggiunto per purge_cooldown iterazioni (max_age se
+    non indicato), e l'attesa raddoppia a ogni nuova rimozione dello stesso taglio.
+    """
+    def __init__(self, min_efficacy=1e-4, max_age=3, tolerance=1e-6, purge_cooldown=None):
+        self.min_efficacy = min_efficacy
+        # Numero di iterazioni consecutive non attive dopo cui un taglio viene rimosso
+        self.max_age = max_age
+        self.purge_cooldown = purge_cooldown if purge_cooldown is not None else max_age
+        self.tolerance = tolerance
+        self.active = {}   # nome taglio -> {'cut', 'key', 'age'}
+        self._keys = set()
+        # Tagli rimossi per età: hash -> {'times': rimozioni, 'wait': iterazioni prima di poterlo riaggiungere}
+        self._purged = {}
+        self.n_duplicates = 0
+        self.n_filtered = 0
+        self.n_removed = 0
+        self.n_purged_rejected = 0
+
+    @staticmethod
+    def cut_key(cut: dict, digits=9):
+        """Hash del taglio normalizzato: coefficienti e rhs divisi per il massimo coefficiente."""
+        scale = max((abs(a) for a in cut['coeffs']), default=1.0) or 1.0
+        terms = tuple(sorted((name, round(a / scale, digits)) for name, a in zip(cut['indices'], cut['coeffs'])))
+        return hash((terms, round(cut['rhs'] / scale, digits), cut['sense']))
+
+    @staticmethod
+    def violation(cut: dict, values: dict):
+        """Violazione del taglio nel punto LP corrente (>0 se violato), nella scala dei suoi coefficienti."""
+        lhs = sum(a * values.get(name, 0.0) for name, a in zip(cut['indices'], cut['coeffs']))
+        return cut['rhs'] - lhs if cut['sense'] == 'G' else lhs - cut['rhs']
+
+    @staticmethod
+    def efficacy(cut: dict, values: dict):
+        """Distanza euclidea del punto LP corrente dall'iperpiano del taglio (>0 se violato)."""
+        norm = math.sqrt(sum(a * a for a in cut['coeffs']))
+        if norm == 0:
+            return 0.0
+        return CutPool.violation(cut, values) / norm
+
+    def select(self, cuts: list, values: dict):
+        """
+        Restituisce i tagli non duplicati e abbastanza efficaci, ordinati per efficacia decrescente.
+        I tagli rimossi per età ancora in attesa vengono scartati.
+        """
+        selected, seen = [], set()
+        for cut in cuts:
+            key = self.cut_key(cut)
+            if key in self._keys or key in seen:
+                self.n_duplicates += 1
+                continue
+            if self._purged.get(key, {}).get('wait', 0) > 0:
+                self.n_purged_rejected += 1
+                continue
+            cut_efficacy = self.efficacy(cut, values)
+            if cut_efficacy < self.min_efficacy:
+                self.n_filtered += 1
+                continue
+            seen.add(key)
+            selected.append({**cut, 'efficacy': cut_efficacy})
+        selected.sort(key=lambda c: c['efficacy'], reverse=True)
+        return selected
+
+    def register(self, name: str, cut: dict):
+        """Registra un taglio effettivamente aggiunto all'LP."""
+        key = self.cut_key(cut)
+        self.active[name] = {'cut': cut, 'key': key, 'age': 0}
+        self._keys.add(key)
+
+    def age_and_purge(self, prob: cplex.Cplex):
+        """
+        Invecchia i tagli non attivi (slack non nullo) nella soluzione corrente
+        e rimuove dall'LP quelli che hanno superato max_age. Restituisce i nomi rimossi.
+        Va chiamato una volta per iterazione: scandisce anche l'attesa dei tagli già rimossi.
+        """
+        for entry in self._purged.values():
+            entry['wait'] = max(0, entry['wait'] - 1)
+        if not self.active:
+            return []
+        names = list(self.active)
+        slacks = prob.solution.get_linear_slacks(names)
+
+        expired = []
+        for name, slack in zip(names, slacks):
+            entry = self.active[name]
+            entry['age'] = entry['age'] + 1 if abs(slack) > self.tolerance else 0
+            if entry['age'] >= self.max_age:
+                expired.append(name)
+
+        if expired:
+            prob.linear_constraints.delete(expired)
+            for name in expired:
+                key = self.active.pop(name)['key']
+                self._keys.discard(key)
+                entry = self._purged.setdefault(key, {'times': 0, 'wait': 0})
+                entry['times'] += 1
+                entry['wait'] = self.purge_cooldown * 2 ** (entry['times'] - 1)
+            self.n_removed += len(expired)
+        return expired
+
+    def summary(self):
+        return {
+            'active_cuts': len(self.active),
+            'duplicates_rejected': self.n_duplicates,
+            'filtered_by_efficacy': self.n_filtered,
+            'removed_by_aging': self.n_removed,
+            'purged_rejected': self.n_purged_rejected,
+        }
@@ -1,12 +1,24 @@
-import datetime
+import json
+import math
+import threading
 from fractions import Fraction
 
 import cplex
 import numpy as np
-from algorithm.solver import Solver, print_solution
+from algorithm.solver import (Solver, print_solution, is_integral_objective, objective_scale_factor,
+                              rounded_dual_bound)
+from algorithm.heuristics import (rounding_repair, dive, HeuristicScheduler, Heuristic, HeuristicContext,
+                                  candidate_from_result)
+from algorithm.cutPool import CutPool
+from algorithm.options import SolverOptions
+from algorithm.strategy import select_strategy
+from algorithm.presolve import presolve_model
 from config import *
 from utility.facilityLocation import FacilityLocationModel
 from utility.utils import get_statistics, modulus
+from utility.errors import SolverError
+from utility.profiling import NULL_PROFILER
+from utility.clock import WALL_CLOCK
 
 
 def _print_analysis_results(instance_name: str, results: dict):
@@ -37,12 +49,244 @@ class Gomory:
     Contiene la logica per il ciclo iterativo e per la generazione
     di diverse famiglie di tagli.
     """
-    def __init__(self, model: FacilityLocationModel):
+    def __init__(self, model: FacilityLocationModel, event_log=None,
+                 heuristics_forced=None, heuristics_disabled=None, profiler=None,
+                 options: SolverOptions = None, clock=None):
         self.model = model
-        self.solver = Solver(self.model)
+        # Parametri del ciclo (vedi algorithm.options); heuristics_forced/disabled hanno la precedenza
+        self.options = (options if options is not None else SolverOptions()).validate()
+        self.solver = Solver(self.model, debug=self.options.debug, gub_branching=self.options.gub_branching,
+                             branching=self.options.branching_strategy(), time_limit=self.options.tree_time_limit)
         # Questo attributo è importante per distinguere le variabili originali
         # dalle variabili di slack/ausiliarie.
         self.n_cols_original = 0
+        # Writer opzionale (file-like) su cui emettere gli eventi del solve come JSON lines
+        self.event_log = event_log
+        self._solve_start = None
+        # Profiler opzionale (utility.profiling.SolveProfiler) che misura il tempo per fase
+        self.profiler = profiler if profiler is not None else NULL_PROFILER
+        # Orologio per tempi e limiti (utility.clock): reale, virtuale o a unità di lavoro
+        self.clock = clock if clock is not None else WALL_CLOCK
+        # Diagnostica dei fallimenti recuperati durante l'ultimo solve (fase, messaggio)
+        self.diagnostics = []
+        # Miglior soluzione intera trovata dalle euristiche primali
+        self.incumbent = None
+        self.heuristics_forced = self.options.heuristics_forced if heuristics_forced is None else heuristics_forced
+        self.heuristics_disabled = self.options.heuristics_disabled if heuristics_disabled is None else heuristics_disabled
+        self.heuristic_scheduler = None
+        # Euristiche registrate dall'utente (nome -> Heuristic) e quelle da eseguire sempre
+        self.user_heuristics = {}
+        self.user_heuristics_forced = set()
+        # Funzioni chiamate a ogni nuovo incumbent (vedi add_incumbent_listener e algorithm.anytime)
+        self.incumbent_listeners = []
+        # cplex.Aborter opzionale: abort() ferma il branch and bound, l'LP corrente e il ciclo dei tagli
+        self.aborter = None
+        self._best_published = None
+        self._publish_lock = threading.Lock()
+        self._heuristic_round = 0
+        self.cut_pool = None
+        # Bound globali ristretti col reduced-cost fixing: indice colonna -> (lb, ub)
+        self.fixed_bounds = {}
+        # Obiettivo a valori interi (bound LP arrotondabile) e fattore di scala applicato ai costi dell'LP
+        self.integral_objective = False
+        self.objective_scale = 1.0
+        # Strategia effettivamente usata nell'ultimo solve (metodo LP, tagli, motivazioni)
+        self.strategy = None
+
+
+    def _emit_event(self, event: str, **data):
+        """Scrive un evento del solve come riga JSON, se è stato fornito un event_log."""
+        if self.event_log is None:
+            return
+        elapsed_ms = 0.0
+        if self._solve_start is not None:
+            elapsed_ms = self.clock.elapsed_ms(self._solve_start)
+        record = {'event': event, 'time_ms': round(elapsed_ms, 3), **data}
+        self.event_log.write(json.dumps(record) + "\n")
+        self.event_log.flush()
+
+
+    def _record_failure(self, stage: str, error: BaseException, tot_stats=None):
+        """Registra un fallimento recuperato e lo associa all'ultima statistica disponibile."""
+        message = f"{type(error).__name__}: {error}"
+        print(f"AVVISO: fallimento recuperato in '{stage}' ({message}). Si prosegue con il miglior risultato disponibile.")
+        self.diagnostics.append({'stage': stage, 'error': message})
+        if tot_stats:
+            tot_stats[-1]['failure'] = f"{stage}: {message}"
+        self._emit_event('failure', stage=stage, error=message)
+
+
+    def _solve_lp(self, mkp: cplex.Cplex):
+        """Risolve il rilassamento e addebita all'orologio le iterazioni del simplesso (più una per la chiamata)."""
+        with self.profiler.phase('lp_solve'):
+            mkp.solve()
+        self.clock.charge(1 + mkp.solution.progress.get_num_iterations())
+
+
+    def _safe_generate(self, generator, mkp: cplex.Cplex, tot_stats):
+        """Esegue un generatore di tagli: se fallisce, il round prosegue senza i suoi tagli."""
+        try:
+            with self.profiler.phase('cut_separation'):
+                return generator(mkp)
+        except Exception as e:
+            self._record_failure(generator.__name__, e, tot_stats)
+            return []
+
+
+    def _heuristics(self):
+        """Euristiche primali disponibili: nome -> funzione(mkp) che restituisce un candidato o None."""
+        return {
+            'rounding_repair': lambda mkp: rounding_repair(self.model, mkp.solution.get_values()[:self.n_cols_original]),
+            'dive_fractional': lambda mkp: dive(self.model, mkp, 'fractional', time_limit=self.options.diving_time_limit,
+                                                clock=self.clock),
+            'dive_coefficient': lambda mkp: dive(self.model, mkp, 'coefficient', time_limit=self.options.diving_time_limit,
+                                                 clock=self.clock),
+            # Il diving guidato ha senso solo se esiste già un incumbent verso cui arrotondare
+            'dive_guided': lambda mkp: (dive(self.model, mkp, 'guided', self.incumbent, self.options.diving_time_limit,
+                                             clock=self.clock)
+                                        if self.incumbent is not None else None),
+        }
+
+
+    def add_incumbent_listener(self, listener):
+        """
+        listener(objective=..., values=..., source=..., time_ms=...) viene chiamata per ogni soluzione
+        intera migliorante: dal branch and bound dell'ILP di riferimento e dalle euristiche primali.
+        Può essere chiamata da thread di CPLEX: deve essere rapida e thread-safe.
+        """
+        self.incumbent_listeners.append(listener)
+        return self
+
+    def remove_incumbent_listener(self, listener):
+        """Rimuove un listener registrato con add_incumbent_listener (se presente)."""
+        if listener in self.incumbent_listeners:
+            self.incumbent_listeners.remove(listener)
+        return self
+
+    def _stop_requested(self):
+        return self.aborter is not None and self.aborter.is_aborted()
+
+    def _publish_incumbent(self, objective, values, source):
+        with self._publish_lock:
+            if self._best_published is not None and objective >= self._best_published - NUMERICAL_TOLERANCE:
+                return
+            self._best_published = objective
+            time_ms = self.clock.elapsed_ms(self._solve_start) if self._solve_start is not None else 0.0
+            for listener in self.incumbent_listeners:
+                listener(objective=objective, values=dict(values), source=source, time_ms=time_ms)
+
+    def register_heuristic(self, heuristic: Heuristic, forced=False):
+        """
+        Registra un'euristica primale dell'utente: a ogni round viene eseguita dallo scheduler
+        come le euristiche predefinite, i suoi candidati aggiornano l'incumbent e le sue
+        statistiche compaiono nel riepilogo. forced=True la esegue a ogni occasione.
+        """
+        name = heuristic.name
+        if not name:
+            raise ValueError("L'euristica deve avere un nome (attributo name).")
+        if name in self._heuristics() or name in self.user_heuristics:
+            raise ValueError(f"Esiste già un'euristica di nome '{name}'.")
+        self.user_heuristics[name] = heuristic
+        if forced:
+            self.user_heuristics_forced.add(name)
+        return self
+
+    def _user_heuristic(self, heuristic: Heuristic):
+        """Adatta un'euristica utente alla firma funzione(mkp) -> candidato usata dallo scheduler."""
+        def run(mkp):
+            context = HeuristicContext(
+                model=self.model, lp_values=mkp.solution.get_values()[:self.n_cols_original],
+                lp_objective=mkp.solution.get_objective_value() / self.objective_scale,
+                incumbent=self.incumbent, iteration=self._heuristic_round, relaxation=mkp
+            )
+            return candidate_from_result(self.model, heuristic.run(context))
+        return run
+
+    def _run_heuristics(self, mkp: cplex.Cplex, tot_stats):
+        """
+        Esegue le euristiche primali sulla soluzione LP corrente, aggiorna l'incumbent
+        e ne registra il valore ('heuristic_ub') nell'ultima statistica.
+        Il round viene saltato se le euristiche hanno già superato heuristic_time_share del tempo di solve.
+        """
+        def on_candidate(heuristic_name, candidate):
+            self._emit_event('heuristic_call', heuristic=heuristic_name, objective=candidate['objective'])
+            # Le euristiche conoscono solo la struttura UFL: i vincoli aggiuntivi vanno verificati a parte
+            if not self.model.side_constraints_satisfied(candidate['values']):
+                return False
+            if self.incumbent is None or candidate['objective'] < self.incumbent['objective'] - NUMERICAL_TOLERANCE:
+                self.incumbent = candidate
+                self._emit_event('new_incumbent', objective=candidate['objective'], source=heuristic_name)
+                self._publish_incumbent(candidate['objective'], candidate['values'], heuristic_name)
+                return True
+            return False
+
+        def on_failure(heuristic_name, error):
+            self._record_failure(heuristic_name, error, tot_stats)
+
+        # Round del ciclo dei tagli (0 = rilassamento iniziale), passato alle euristiche utente
+        self._heuristic_round = len(tot_stats) - 1
+
+        share = self.options.heuristic_time_share
+        spent = sum(s['total_time'] for s in self.heuristic_scheduler.stats.values())
+        if share < 1.0 and spent > share * (self.clock.now() - self._solve_start):
+            self._emit_event('heuristics_skipped', reason='time_share', spent=spent)
+        else:
+            with self.profiler.phase('heuristics'):
+                self.heuristic_scheduler.run(mkp, on_candidate, on_failure)
+
+        if self.incumbent is not None and tot_stats:
+            tot_stats[-1]['heuristic_ub'] = self.incumbent['objective']
+
+
+    def _reduced_cost_fixing(self, mkp: cplex.Cplex, tot_stats):
+        """
+        Reduced-cost fixing rispetto all'incumbent (minimizzazione): se x_j è al lower bound
+        con costo ridotto d_j > 0, ogni soluzione con x_j >= lb_j + k costa almeno z_LP + k * d_j,
+        quindi ub_j si può ridurre a lb_j + floor((z_inc - z_LP) / d_j) (simmetrico al upper bound).
+        I bound ristretti restano validi per tutte le iterazioni successive.
+        Restituisce il numero di bound modificati.
+        """
+        if not self.options.reduced_cost_fixing or self.incumbent is None:
+            return 0
+
+        # Costi ridotti e valore LP sono nella scala dell'LP: anche l'incumbent va riscalato
+        gap = self.incumbent['objective'] * self.objective_scale - mkp.solution.get_objective_value()
+        if gap < -NUMERICAL_TOLERANCE:
+            return 0
+        n_cols = self.n_cols_original
+        values = mkp.solution.get_values(0, n_cols - 1)
+        reduced_costs = mkp.solution.get_reduced_costs(0, n_cols - 1)
+        lower_bounds = mkp.variables.get_lower_bounds(0, n_cols - 1)
+        upper_bounds = mkp.variables.get_upper_bounds(0, n_cols - 1)
+
+        new_lower, new_upper = [], []
+        for j, (x, d, lb, ub) in enumerate(zip(values, reduced_costs, lower_bounds, upper_bounds)):
+            if abs(d) <= NUMERICAL_TOLERANCE or lb == ub:
+                continue
+            # Margine intero: la tolleranza evita di escludere soluzioni di pari costo dell'incumbent
+            steps = math.floor((gap + NUMERICAL_TOLERANCE) / abs(d))
+            if d > 0 and abs(x - lb) <= NUMERICAL_TOLERANCE and lb + steps < ub:
+                new_upper.append((j, float(lb + steps)))
+            elif d < 0 and abs(x - ub) <= NUMERICAL_TOLERANCE and ub - steps > lb:
+                new_lower.append((j, float(ub - steps)))
+
+        if new_upper:
+            mkp.variables.set_upper_bounds(new_upper)
+        if new_lower:
+            mkp.variables.set_lower_bounds(new_lower)
+        for j, bound in new_upper:
+            self.fixed_bounds[j] = (self.fixed_bounds.get(j, (lower_bounds[j], None))[0], bound)
+        for j, bound in new_lower:
+            self.fixed_bounds[j] = (bound, self.fixed_bounds.get(j, (None, upper_bounds[j]))[1])
+
+        n_changed = len(new_upper) + len(new_lower)
+        if tot_stats:
+            tot_stats[-1]['fixed_by_reduced_cost'] = sum(1 for lb, ub in self.fixed_bounds.values() if lb == ub)
+        if n_changed:
+            print(f"  -> Reduced-cost fixing: {n_changed} bound ristretti "
+                  f"({len(self.fixed_bounds)} variabili con bound globali modificati).")
+            self._emit_event('reduced_cost_fixing', changed=n_changed, total=len(self.fixed_bounds))
+        return n_changed
 
 
     #metodi privati per la scelta della modalità di taglio
@@ -177,17 +421,321 @@ class Gomory:
             print(f"ERRORE CPLEX durante la generazione dei tagli GMI: {e}")
         return generated_cuts
 
+    def _mir_round(self, int_coeffs, cont_coeffs, beta, delta):
+        """
+        Applica l'arrotondamento MIR alla riga sum a_j z_j + sum c_k t_k <= beta divisa per delta
+        (z interi >= 0, t continue >= 0). Restituisce (coeff. interi, coeff. continui, rhs) oppure None.
+        """
+        f0 = beta / delta - math.floor(beta / delta)
+        if f0 < NUMERICAL_TOLERANCE or 1 - f0 < NUMERICAL_TOLERANCE:
+            return None
+
+        new_int = {}
+        for j, a in int_coeffs.items():
+            scaled = a / delta
+            f_j = scaled - math.floor(scaled)
+            new_int[j] = math.floor(scaled) + max(0.0, f_j - f0) / (1 - f0)
+        # Le continue con coefficiente positivo si possono scartare, le negative vengono scalate
+        new_cont = {k: (c / delta) / (1 - f0) for k, c in cont_coeffs.items() if c < 0}
+        return new_int, new_cont, math.floor(beta / delta)
+
+
+    def _generate_mir_cuts(self, prob: cplex.Cplex):
+        """
+        Genera tagli MIR (c-MIR) sulle aggregazioni di righe date dal tableau ottimo:
+        ogni riga del tableau è una combinazione delle righe originali scelta dalla base.
+        Le variabili non di base al bound superiore vengono complementate, gli slack sono
+        trattati come continui e si prova una serie di fattori di scala delta.
+        """
+        generated_cuts = []
+        try:
+            col_status, row_status = prob.solution.basis.get_basis()
+            head, basic_values = prob.solution.advanced.get_basis_header()
+            values = prob.solution.get_values()
+            all_var_names = prob.variables.get_names()
+            n_cols = len(all_var_names)
+
+            rows = prob.linear_constraints.get_rows()
+            rhs = prob.linear_constraints.get_rhs()
+            senses = prob.linear_constraints.get_senses()
+            at_upper = prob.solution.basis.status.at_upper_bound
+            basic = prob.solution.basis.status.basic
+
+            for i, var_idx in enumerate(head):
+                # Solo variabili strutturali (intere) di base con valore frazionario
+                if var_idx < 0 or not all_var_names[var_idx].startswith('x'):
+                    continue
+                beta = basic_values[i]
+                if beta - math.floor(beta) < NUMERICAL_TOLERANCE or math.ceil(beta) - beta < NUMERICAL_TOLERANCE:
+                    continue
+
+                tableau_row = prob.solution.advanced.binvarow(i)
+                binv_row = prob.solution.advanced.binvrow(i)
+
+                # Riga trasformata: tutte le non di base valgono 0 nel punto corrente
+                int_coeffs, complemented = {var_idx: 1.0}, set()
+                for j in range(n_cols):
+                    if j == var_idx or col_status[j] == basic or abs(tableau_row[j]) < NUMERICAL_TOLERANCE:
+                        continue
+                    if col_status[j] == at_upper:
+                        complemented.add(j)
+                        int_coeffs[j] = -tableau_row[j]
+                    else:
+                        int_coeffs[j] = tableau_row[j]
+
+                cont_coeffs = {}
+                for k, coeff in enumerate(binv_row):
+                    if row_status[k] == basic or abs(coeff) < NUMERICAL_TOLERANCE:
+                        continue
+                    # Slack CPLEX: s = rhs - a x; per le righe 'G' s <= 0, si usa t = -s >= 0
+                    cont_coeffs[k] = -coeff if senses[k] == 'G' else coeff
+
+                # Fattori di scala: 1 e i coefficienti interi più grandi (con le loro metà)
+                candidates = {1.0}
+                for a in sorted((abs(a) for a in int_coeffs.values() if abs(a) > NUMERICAL_TOLERANCE), reverse=True)[:3]:
+                    candidates.update({a, a / 2, a / 4})
+
+                best_cut, best_violation = None, NUMERICAL_TOLERANCE
+                for delta in candidates:
+                    rounded = self._mir_round(int_coeffs, cont_coeffs, beta, delta)
+                    if rounded is None:
+                        continue
+                    cut = self._mir_to_structural(rounded, complemented, rows, rhs, senses, n_cols)
+                    violation = sum(a * values[j] for j, a in cut[0].items()) - cut[1]
+                    if violation > best_violation:
+                        best_cut, best_violation = cut, violation
+
+                if best_cut is not None:
+                    coeffs, cut_rhs = best_cut
+                    indices = [j for j, a in coeffs.items() if abs(a) > NUMERICAL_TOLERANCE]
+                    if indices:
+                        generated_cuts.append({
+                            'indices': [all_var_names[j] for j in indices],
+                            'coeffs': [coeffs[j] for j in indices],
+                            'rhs': cut_rhs, 'sense': 'L', 'violation': best_violation
+                        })
+        except cplex.CplexError as e:
+            print(f"ERRORE CPLEX durante la generazione dei tagli MIR: {e}")
+        return generated_cuts
+
+
+    def _mir_to_structural(self, rounded, complemented, rows, rhs, senses, n_cols):
+        """Riporta un taglio MIR nello spazio delle variabili strutturali (scomplementa e sostituisce gli slack)."""
+        new_int, new_cont, cut_rhs = rounded
+        coeffs = [0.0] * n_cols
+
+        for j, g in new_int.items():
+            if j in complemented:
+                # g * (1 - x_j)
+                coeffs[j] -= g
+                cut_rhs -= g
+            else:
+                coeffs[j] += g
+
+        for k, g in new_cont.items():
+            # Righe 'L': t = rhs - a x; righe 'G': t = a x - rhs
+            sign = -1.0 if senses[k] == 'G' else 1.0
+            for j, a in zip(rows[k].ind, rows[k].val):
+                coeffs[j] -= sign * g * a
+            cut_rhs -= sign * g * rhs[k]
+
+        return {j: a for j, a in enumerate(coeffs) if a != 0.0}, cut_rhs
+
+
+    def _generate_zero_half_cuts(self, prob: cplex.Cplex, max_rows_per_column=20, max_cuts=200):
+        """
+        Genera tagli {0, 1/2}-Chvátal-Gomory con un'euristica di riduzione modulo 2:
+        si combinano (con peso 1/2) fino a tre righe intere poco lasche che condividono
+        variabili con coefficiente dispari; i coefficienti dispari rimasti vengono resi pari
+        con i vincoli di bound (0 <= x_j <= 1) e, se il rhs risulta dispari, si arrotonda.
+        Il taglio è violato se la somma degli slack delle righe usate è minore di 1.
+        """
+        generated_cuts = []
+        try:
+            values = prob.solution.get_values()
+            all_var_names = prob.variables.get_names()
+            rows = prob.linear_constraints.get_rows()
+            rhs = prob.linear_constraints.get_rhs()
+            senses = prob.linear_constraints.get_senses()
+
+            def is_integer(a):
+                return abs(a - round(a)) < NUMERICAL_TOLERANCE
+
+            # Righe candidate nella forma a x <= b con dati interi e slack < 1
+            candidates = []
+            for row, b, sense in zip(rows, rhs, senses):
+                if sense not in ('L', 'G'):
+                    continue
+                sign = -1 if sense == 'G' else 1
+                coeffs = {j: sign * a for j, a in zip(row.ind, row.val) if abs(a) > NUMERICAL_TOLERANCE}
+                if not coeffs or not is_integer(b) or not all(is_integer(a) for a in coeffs.values()):
+                    continue
+                coeffs = {j: int(round(a)) for j, a in coeffs.items()}
+                slack = sign * b - sum(a * values[j] for j, a in coeffs.items())
+                if slack < 1 - NUMERICAL_TOLERANCE:
+                    candidates.append((coeffs, int(round(sign * b)), max(0.0, slack)))
+
+            # Combinazioni: righe singole e coppie che condividono una colonna dispari
+            by_column = {}
+            for pos, (coeffs, _, slack) in enumerate(candidates):
+                for j, a in coeffs.items():
+                    if a % 2:
+                        by_column.setdefault(j, []).append(pos)
+            for j in by_column:
+                by_column[j] = sorted(by_column[j], key=lambda pos: candidates[pos][2])[:max_rows_per_column]
+            combos = {frozenset([pos]) for pos in range(len(candidates))}
+            pairs = set()
+            for positions in by_column.values():
+                for a_idx in range(len(positions)):
+                    for b_idx in range(a_idx + 1, len(positions)):
+                        pairs.add(frozenset([positions[a_idx], positions[b_idx]]))
+            combos |= pairs
+            # Terne (chiusura di cicli dispari): terza riga che tocca entrambe le colonne rimaste dispari
+            for pair in pairs:
+                parity = {}
+                for pos in pair:
+                    for j, a in candidates[pos][0].items():
+                        parity[j] = (parity.get(j, 0) + a) % 2
+                odd = [j for j, odd_coeff in parity.items() if odd_coeff]
+                if len(odd) != 2:
+                    continue
+                for pos in set(by_column.get(odd[0], [])) & set(by_column.get(odd[1], [])):
+                    if pos not in pair:
+                        combos.add(pair | {pos})
+
+            seen = set()
+            for combo in combos:
+                combined, total_rhs, total_slack = {}, 0, 0.0
+                for pos in combo:
+                    coeffs, b, slack = candidates[pos]
+                    for j, a in coeffs.items():
+                        combined[j] = combined.get(j, 0) + a
+                    total_rhs += b
+                    total_slack += slack
+                if total_slack >= 1 - NUMERICAL_TOLERANCE:
+                    continue
+
+                # Rende pari i coefficienti dispari: -x_j <= 0 costa x_j, x_j <= 1 costa 1 - x_j e cambia la parità del rhs
+                odd_columns = [j for j, a in combined.items() if a % 2]
+                use_upper = {j: (1 - values[j]) < values[j] for j in odd_columns}
+                rhs_parity = (total_rhs + sum(use_upper.values())) % 2
+                if rhs_parity == 0:
+                    if not odd_columns:
+                        continue
+                    # Serve un rhs dispari: si inverte la scelta più economica
+                    flip = min(odd_columns, key=lambda j: abs((1 - values[j]) - values[j]))
+                    use_upper[flip] = not use_upper[flip]
+                bound_slack = sum((1 - values[j]) if use_upper[j] else values[j] for j in odd_columns)
+                if total_slack + bound_slack >= 1 - NUMERICAL_TOLERANCE:
+                    continue
+
+                for j in odd_columns:
+                    if use_upper[j]:
+                        combined[j] += 1
+                        total_rhs += 1
+                    else:
+                        combined[j] -= 1
+
+                cut = {j: a // 2 for j, a in combined.items() if a // 2 != 0}
+                cut_rhs = total_rhs // 2  # total_rhs è dispari: arrotondamento per difetto di (rhs) / 2
+                key = (tuple(sorted(cut.items())), cut_rhs)
+                if not cut or key in seen:
+                    continue
+                seen.add(key)
+
+                violation = sum(a * values[j] for j, a in cut.items()) - cut_rhs
+                if violation > NUMERICAL_TOLERANCE:
+                    generated_cuts.append({
+                        'indices': [all_var_names[j] for j in cut],
+                        'coeffs': [float(a) for a in cut.values()],
+                        'rhs': float(cut_rhs), 'sense': 'L', 'violation': violation
+                    })
+                    if len(generated_cuts) >= max_cuts:
+                        break
+        except cplex.CplexError as e:
+            print(f"ERRORE CPLEX durante la generazione dei tagli zero-half: {e}")
+        return generated_cuts
+
+
         #metodo principale di risoluzione
 
-    def solve_problem(self, instance_path_str: str, cut_mode: str):
+    def _resolve_strategy(self, c, A, b, cut_mode):
+        """Applica la scelta automatica dove richiesta ('AUTO'/'auto'); le scelte esplicite hanno la precedenza."""
+        lp_method = self.options.lp_method
+        reasons = []
+        if cut_mode == 'AUTO' or lp_method == 'auto':
+            if A is None:
+                _, A, b = self.solver.get_problem_data(maximize=False)
+            auto = select_strategy(c, A, b)
+            reasons = auto['reasons']
+            if cut_mode == 'AUTO':
+                cut_mode = auto['cut_mode']
+            if lp_method == 'auto':
+                lp_method = auto['lp_method']
+            print(f"Strategia automatica: metodo LP {lp_method}, tagli {cut_mode} ({'; '.join(reasons)}).")
+        self.strategy = {'lp_method': lp_method, 'cut_mode': cut_mode, 'reasons': reasons}
+        return lp_method, cut_mode
+
+    def solve_problem(self, instance_path_str: str, cut_mode: str = None):
+        cut_mode = cut_mode or self.options.cut_mode
         instance_path = Path(instance_path_str)
         name = instance_path.stem
 
-        c, A, b = self.solver.get_problem_data(maximize=False)
-        self.n_cols_original, n_rows = len(c), len(b)
-
-        optimal_sol = self.solver.determine_optimal(instance_path, maximize=False)
-        if optimal_sol is None: return []
+        self._solve_start = self.clock.now()
+        self.diagnostics = []
+        self.incumbent = None
+        self._best_published = None
+        if self.incumbent_listeners:
+            self.solver.on_incumbent = lambda objective, values: self._publish_incumbent(objective, values,
+                                                                                         'reference_ilp_search')
+        heuristics = self._heuristics()
+        heuristics.update({name: self._user_heuristic(h) for name, h in self.user_heuristics.items()})
+        self.heuristic_scheduler = HeuristicScheduler(heuristics,
+                                                      forced=list(self.heuristics_forced) + sorted(self.user_heuristics_forced),
+                                                      disabled=self.heuristics_disabled, clock=self.clock)
+        self.cut_pool = CutPool(min_efficacy=self.options.cut_min_efficacy, max_age=self.options.cut_max_age)
+        self.fixed_bounds = {}
+        self._emit_event('solve_start', instance=name, cut_mode=cut_mode)
+
+        # Senza presolve l'LP viene costruito riga per riga (add_problem_rows): la matrice densa
+        # serve solo alla scelta automatica della strategia e viene costruita solo in quel caso
+        c, A, b = self.solver.get_costs(maximize=False), None, None
+        self.n_cols_original = len(c)
+        lb, ub = [0.0] * self.n_cols_original, [1.0] * self.n_cols_original
+        self.presolve = None
+        # Il presolve del ciclo dei tagli elimina righe e restringe bound, ma non aggrega colonne:
+        # l'LP resta sulle variabili originali x{i}, quindi valori e duali non passano da postsolve
+        if self.options.exact_presolve:
+            try:
+                with self.profiler.phase('presolve'):
+                    self.presolve = presolve_model(self.model, self.options.presolve_time_limit, self.clock)
+            except SolverError as e:
+                print(f"Presolve: {e}")
+                self._emit_event('solve_end', status=e.status.value)
+                return []
+            c, A, b, lb, ub = self.presolve.c, self.presolve.A, self.presolve.b, self.presolve.lb, self.presolve.ub
+            n_rows = len(b)
+            print(f"Presolve esatto: {self.presolve.n_rows_original} -> {n_rows} righe {self.presolve.removed}"
+                  + ("" if self.presolve.complete else " (interrotto per limite di tempo)"))
+            self._emit_event('presolve', rows_before=self.presolve.n_rows_original, rows_after=n_rows,
+                             **self.presolve.removed)
+        lp_method, cut_mode = self._resolve_strategy(c, A, b, cut_mode)
+        self._emit_event('strategy', **self.strategy)
+        self.integral_objective = is_integral_objective(c)
+        self.objective_scale = objective_scale_factor(c)
+        if self.objective_scale != 1.0:
+            print(f"Obiettivo mal scalato: costi dell'LP moltiplicati per {self.objective_scale:g}.")
+
+        self.solver.aborter = self.aborter
+        try:
+            with self.profiler.phase('reference_ilp'):
+                optimal_sol = self.solver.determine_optimal(instance_path, maximize=False)
+        except SolverError as e:
+            print(f"Ottimo di riferimento non disponibile ({e.status.value}): {e}")
+            self._emit_event('solve_end', status=e.status.value)
+            return []
+        self._emit_event('new_incumbent', objective=optimal_sol, source='reference_ilp')
+        self._publish_incumbent(optimal_sol, self.solver.optimal_values, 'reference_ilp')
 
         tot_stats = []
         try:
@@ -197,46 +745,64 @@ class Gomory:
                 mkp.set_problem_name(name + "_LP_Relaxation")
                 mkp.objective.set_sense(mkp.objective.sense.minimize)
                 mkp.parameters.preprocessing.presolve.set(0)
-                mkp.parameters.lpmethod.set(mkp.parameters.lpmethod.values.primal)
+                mkp.parameters.lpmethod.set(getattr(mkp.parameters.lpmethod.values, lp_method))
+                if self.aborter is not None:
+                    mkp.use_aborter(self.aborter)
 
                 var_names = [f"x{i}" for i in  range(self.n_cols_original)]
-                mkp.variables.add(obj=c, lb=[0.0] * self.n_cols_original, ub=[1.0] * self.n_cols_original, names=var_names)
-                mkp.linear_constraints.add(
-                    lin_expr=[cplex.SparsePair(ind=list(range(self.n_cols_original)), val=A[i]) for i in range(n_rows)],
-                    rhs=b.tolist(), senses=['L'] * n_rows, names=[f"c{i}" for i in range(n_rows)]
-                )
+                mkp.variables.add(obj=(c * self.objective_scale).tolist(), lb=lb, ub=ub, names=var_names)
+                if self.presolve is None:
+                    n_rows = self.solver.add_problem_rows(mkp)
+                else:
+                    mkp.linear_constraints.add(
+                        lin_expr=[cplex.SparsePair(ind=[j for j, a in enumerate(row) if a != 0.0],
+                                                   val=[float(a) for a in row if a != 0.0]) for row in A],
+                        rhs=b.tolist(), senses=['L'] * n_rows, names=[f"c{i}" for i in range(n_rows)]
+                    )
 
                 # 2. Risoluzione del rilassamento LP iniziale
-                start_time = datetime.datetime.now()
-                mkp.solve()
-                sol, sol_type, status = print_solution(mkp)
-                elapsed_time = (datetime.datetime.now() - start_time).total_seconds() * 1000
+                start_time = self.clock.now()
+                self._solve_lp(mkp)
+                sol, sol_type, status = print_solution(mkp, self.objective_scale)
+                elapsed_time = self.clock.elapsed_ms(start_time)
                 stats_iter_0 = get_statistics(name, self.n_cols_original, n_rows, optimal_sol, sol, sol_type, status, 0, elapsed_time, 0)
+                stats_iter_0['rounded_lp_bound'] = rounded_dual_bound(sol, self.integral_objective)
                 tot_stats.append(stats_iter_0)
+                self._emit_event('bound_update', bound=sol, gap=stats_iter_0['relative_gap'], iteration=0, status=status)
 
                 if status != 'optimal':
                     print("ERRORE: Rilassamento iniziale non risolto ottimamente.")
                     return tot_stats
+                self._run_heuristics(mkp, tot_stats)
+                with self.profiler.phase('reduced_cost_fixing'):
+                    self._reduced_cost_fixing(mkp, tot_stats)
 
                 # 3. Ciclo iterativo di aggiunta dei tagli
                 iteration, total_time, num_total_cuts = 1, elapsed_time, 0
+                cut_start = self.clock.now()
                 MAX_TOTAL_CUTS = 500 # Limite di sicurezza sul numero totale di tagli
 
-                while (total_time <= TIME_LIMIT and num_total_cuts <= MAX_TOTAL_CUTS and
-                       modulus(sol, optimal_sol) / (abs(optimal_sol) + 1e-6) > THRESHOLD_GAP and
-                       status == "optimal" and iteration <= MAX_ITERATIONS):
+                while (self.clock.now() - self._solve_start <= self.options.time_limit and num_total_cuts <= MAX_TOTAL_CUTS and
+                       modulus(rounded_dual_bound(sol, self.integral_objective), optimal_sol) / (abs(optimal_sol) + 1e-6) > self.options.threshold_gap and
+                       status == "optimal" and iteration <= self.options.max_iterations):
 
-                    start_iteration_time = datetime.datetime.now()
+                    start_iteration_time = self.clock.now()
+                    if self._stop_requested():
+                        print("STOP: Solve annullato.")
+                        break
+                    if self.options.root_cut_time and start_iteration_time - cut_start > self.options.root_cut_time:
+                        print("STOP: Budget di tempo dei tagli alla radice esaurito.")
+                        break
 
                     # 3a. Genera i tagli usando i metodi della classe
                     cuts_to_process = []
                     if cut_mode == 'GFC':
-                        cuts_to_process = self._generate_gomory_fractional_cuts(mkp)
+                        cuts_to_process = self._safe_generate(self._generate_gomory_fractional_cuts, mkp, tot_stats)
                     elif cut_mode == 'GMI':
-                        cuts_to_process = self._generate_gomory_mixed_integer_cuts(mkp)
+                        cuts_to_process = self._safe_generate(self._generate_gomory_mixed_integer_cuts, mkp, tot_stats)
                     elif cut_mode == 'BEST':
-                        cuts_gmi= self._generate_gomory_mixed_integer_cuts(mkp)
-                        cuts_gfc = self._generate_gomory_fractional_cuts(mkp)
+                        cuts_gmi= self._safe_generate(self._generate_gomory_mixed_integer_cuts, mkp, tot_stats)
+                        cuts_gfc = self._safe_generate(self._generate_gomory_fractional_cuts, mkp, tot_stats)
                         # Combina i migliori tagli da entrambi i metodi invece di scegliere un solo tipo
                         cuts_gmi.sort(key=lambda x: x.get('violation', 0), reverse=True)
                         cuts_gfc.sort(key=lambda x: x.get('violation', 0), reverse=True)
@@ -249,7 +815,16 @@ class Gomory:
                                 cuts_to_process.append(cuts_gmi[i])
                             if i < len_gfc:
                                 cuts_to_process.append(cuts_gfc[i])
+                    elif cut_mode == 'MIR':
+                        cuts_to_process = self._safe_generate(self._generate_mir_cuts, mkp, tot_stats)
+                    elif cut_mode == 'ZH':
+                        cuts_to_process = self._safe_generate(self._generate_zero_half_cuts, mkp, tot_stats)
+
 
+                    # Scarta i duplicati di tagli già presenti e quelli poco efficaci sul punto LP corrente
+                    current_values = dict(zip(mkp.variables.get_names(), mkp.solution.get_values()))
+                    with self.profiler.phase('cut_pool'):
+                        cuts_to_process = self.cut_pool.select(cuts_to_process, current_values)
 
                     if not cuts_to_process:
                         print("STOP: Nessun nuovo taglio generato.")
@@ -257,7 +832,7 @@ class Gomory:
 
                     # 3b. Seleziona i tagli migliori e aggiungili
                     cuts_to_process.sort(key=lambda x: (
-                        x.get('violation', 0)  # Secondo criterio: "forza" del taglio
+                        x.get('efficacy', x.get('violation', 0))  # Secondo criterio: "forza" del taglio
                     ), reverse=True)
 
                     cuts_added_this_iteration = 0
@@ -271,16 +846,23 @@ class Gomory:
                     for i, cut_info in enumerate(cuts_to_process):
 
                         cut_name=f"{cut_mode.lower()}_{iteration}_{i}"
-                        mkp.linear_constraints.add(
-                            lin_expr=[cplex.SparsePair(ind=cut_info['indices'], val=cut_info['coeffs'])],
-                            senses=[cut_info['sense']], rhs=[cut_info['rhs']],
-                            names=[cut_name]
-                        )
-
-                        # 3c. Risolvi il modello aggiornato
-                        mkp.solve()
+                        try:
+                            mkp.linear_constraints.add(
+                                lin_expr=[cplex.SparsePair(ind=cut_info['indices'], val=cut_info['coeffs'])],
+                                senses=[cut_info['sense']], rhs=[cut_info['rhs']],
+                                names=[cut_name]
+                            )
+
+                            # 3c. Risolvi il modello aggiornato
+                            self._solve_lp(mkp)
+                        except cplex.CplexError as e:
+                            # Fallimento numerico sul singolo taglio: lo si scarta e si continua
+                            self._record_failure(f"resolve {cut_name}", e, tot_stats)
+                            if cut_name in mkp.linear_constraints.get_names():
+                                mkp.linear_constraints.delete(cut_name)
+                            continue
 
-                        current_sol, _, current_status = print_solution(mkp)
+                        current_sol, _, current_status = print_solution(mkp, self.objective_scale)
                         if current_status != 'optimal' or current_sol > optimal_sol + NUMERICAL_TOLERANCE:
                             print(f"AVVISO: Il taglio {i+1} ha causato instabilità.")
                             mkp.linear_constraints.delete(cut_name)
@@ -288,26 +870,57 @@ class Gomory:
                         else:
                             sol= current_sol
                             cuts_added_this_iteration += 1
+                            self.cut_pool.register(cut_name, cut_info)
+                            self._emit_event('bound_update', bound=sol, iteration=iteration, cut=cut_name)
                             print(f"  -> Taglio {i+1} (viol: {cut_info['violation']:.4f}) stabile. Aggiunto. Nuova sol: {sol:.4f}")
                     num_total_cuts += cuts_added_this_iteration
-                    current_stats = get_statistics(name, self.n_cols_original, n_rows + num_total_cuts, optimal_sol, sol, sol_type, status, num_total_cuts, total_time, iteration)
+
+                    # Rimuove i tagli rimasti inattivi per troppe iterazioni
+                    with self.profiler.phase('cut_pool'):
+                        removed_cuts = self.cut_pool.age_and_purge(mkp)
+                    if removed_cuts:
+                        print(f"  -> Rimossi {len(removed_cuts)} tagli inattivi dal rilassamento.")
+                        self._solve_lp(mkp)
+                        sol, _, status = print_solution(mkp, self.objective_scale)
+
+                    current_stats = get_statistics(name, self.n_cols_original, n_rows + len(self.cut_pool.active), optimal_sol, sol, sol_type, status, num_total_cuts, total_time, iteration)
+                    current_stats['rounded_lp_bound'] = rounded_dual_bound(sol, self.integral_objective)
                     tot_stats.append(current_stats)
+                    self._emit_event('cut_round', iteration=iteration, cuts_generated=len(cuts_to_process),
+                                     cuts_added=cuts_added_this_iteration, total_cuts=num_total_cuts,
+                                     bound=sol, gap=current_stats['relative_gap'])
                     if cuts_added_this_iteration == 0 :
                         print("STOP: Nessun taglio valido aggiunto in questa iterazione.")
                         break
+                    self._run_heuristics(mkp, tot_stats)
+                    with self.profiler.phase('reduced_cost_fixing'):
+                        self._reduced_cost_fixing(mkp, tot_stats)
 
                     # 3d. Raccogli statistiche
-                    iteration_time = (datetime.datetime.now() - start_iteration_time).total_seconds() * 1000
+                    iteration_time = self.clock.elapsed_ms(start_iteration_time)
                     total_time += iteration_time
                     iteration += 1
 
 
                 print(f"\n=== FINE RISOLUZIONE (MODALITÀ {cut_mode}) ===")
+                self._emit_event('solve_end', bound=sol, iterations=iteration, total_cuts=num_total_cuts,
+                                 heuristics=self.heuristic_scheduler.summary(), cut_pool=self.cut_pool.summary(),
+                                 fixed_bounds=len(self.fixed_bounds), phases=self.profiler.summary())
                 return tot_stats
 
         except cplex.CplexError as e:
             print(f"ERRORE CPLEX in solve_problem: {e}")
+            self._record_failure("solve_problem", e, tot_stats)
             return tot_stats
+        except KeyboardInterrupt:
+            # L'interruzione dell'utente viene annotata nelle statistiche ma non assorbita: il chiamante si ferma
+            self.diagnostics.append({'stage': 'solve_problem', 'error': 'KeyboardInterrupt'})
+            if tot_stats:
+                tot_stats[-1]['failure'] = "solve_problem: interrotto dall'utente"
+            self._emit_event('failure', stage='solve_problem', error='KeyboardInterrupt')
+            raise
         except Exception as e:
+            # Qualsiasi errore imprevisto non deve perdere le statistiche raccolte
             print(f"ERRORE IMPREVISTO in solve_problem: {e}")
-            raise
\ No newline at end of file
+            self._record_failure("solve_problem", e, tot_stats)
+            return tot_stats
\ No newline at end of file
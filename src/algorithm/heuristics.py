@@ -0,0 +1,339 @@
+import math
+from dataclasses import dataclass
+
+import cplex
+from utility.facilityLocation import FacilityLocationModel
+from utility.solutionCheck import check_solution
+from utility.clock import WALL_CLOCK
+
+# euristiche primali per UFL a partire dalla soluzione del rilassamento LP
+
+
+def assign_to_open(model: FacilityLocationModel, open_facilities):
+    """
+    Dato un insieme di facility aperte, assegna ogni cliente alla facility aperta più economica.
+    Per UFL ogni insieme non vuoto di facility aperte dà una soluzione ammissibile.
+    Restituisce (costo, assegnamento cliente -> facility).
+    """
+    fixed_costs = model.get_fixed_costs()
+    assignment_costs = model.get_assignment_costs()
+
+    cost = sum(fixed_costs[u] for u in open_facilities)
+    assignment = {}
+    for v in range(model.get_num_customers()):
+        best_u = min(open_facilities, key=lambda u: assignment_costs[v][u])
+        assignment[v] = best_u
+        cost += assignment_costs[v][best_u]
+    return float(cost), assignment
+
+
+def solution_values(model: FacilityLocationModel, open_facilities, assignment):
+    """Converte facility aperte e assegnamento nel dizionario nome variabile -> valore."""
+    values = {f"x{i}": 0.0 for i in range(model.get_num_variables())}
+    for u in open_facilities:
+        values[f"x{model.variable_index(u)}"] = 1.0
+    for v, u in assignment.items():
+        values[f"x{model.variable_index(u, v)}"] = 1.0
+    return values
+
+
+def build_candidate(model: FacilityLocationModel, open_facilities):
+    """
+    Costruisce una soluzione candidata dalle facility aperte: assegna i clienti
+    e chiude le facility rimaste senza clienti (risparmio sui costi fissi).
+    """
+    _, assignment = assign_to_open(model, open_facilities)
+    used = sorted(set(assignment.values()))
+    cost, assignment = assign_to_open(model, used)
+    return {
+        'objective': cost,
+        'open_facilities': used,
+        'values': solution_values(model, used, assignment),
+    }
+
+
+def rounding_repair(model: FacilityLocationModel, lp_values, threshold=0.5):
+    """
+    Euristica di arrotondamento e riparazione:
+    1. apre le facility con x_u >= threshold (arrotondamento);
+    2. se nessuna è aperta, apre quella con x_u più alto (riparazione);
+    3. assegna ogni cliente alla facility aperta più economica (shift);
+    4. chiude le facility rimaste senza clienti (propagazione del risparmio sui costi fissi).
+    lp_values è la lista dei valori LP indicizzata come le variabili del modello.
+    Restituisce un dizionario {'objective', 'open_facilities', 'values'} oppure None.
+    """
+    p = model.get_num_facilities()
+    if p == 0:
+        return None
+
+    open_facilities = [u for u in range(p) if lp_values[model.variable_index(u)] >= threshold]
+    if not open_facilities:
+        open_facilities = [max(range(p), key=lambda u: lp_values[model.variable_index(u)])]
+
+    return build_candidate(model, open_facilities)
+
+
+def _variable_locks(lp: cplex.Cplex, n_cols: int):
+    """
+    Calcola per ogni variabile i "lock": quante righe potrebbero essere violate
+    arrotondandola verso il basso (down) o verso l'alto (up).
+    """
+    down_locks, up_locks = [0] * n_cols, [0] * n_cols
+    senses = lp.linear_constraints.get_senses()
+    for row, sense in zip(lp.linear_constraints.get_rows(), senses):
+        for j, a in zip(row.ind, row.val):
+            if j >= n_cols or abs(a) < 1e-12:
+                continue
+            if sense in ('L', 'E'):
+                if a > 0: up_locks[j] += 1
+                else: down_locks[j] += 1
+            if sense in ('G', 'E'):
+                if a > 0: down_locks[j] += 1
+                else: up_locks[j] += 1
+    return down_locks, up_locks
+
+
+def _select_dive_variable(rule, candidates, values, locks, incumbent_values):
+    """
+    Sceglie la variabile da fissare e la direzione secondo la regola di diving:
+    - 'fractional': la meno frazionaria, arrotondata all'intero più vicino;
+    - 'coefficient': quella con meno lock nella direzione di arrotondamento (pareggi per frazionarietà);
+    - 'guided': quella più vicina al valore nell'incumbent, verso cui viene arrotondata.
+    Restituisce (indice, valore a cui fissarla).
+    """
+    def frac(j):
+        return values[j] - math.floor(values[j])
+
+    if rule == 'guided' and incumbent_values is not None:
+        j = min(candidates, key=lambda k: abs(values[k] - incumbent_values[k]))
+        return j, float(round(incumbent_values[j]))
+
+    if rule == 'coefficient':
+        down_locks, up_locks = locks
+
+        def score(k):
+            f = frac(k)
+            # Direzione con meno lock; a parità si sceglie l'arrotondamento più vicino
+            if down_locks[k] < up_locks[k] or (down_locks[k] == up_locks[k] and f < 0.5):
+                return (down_locks[k], f, k, math.floor(values[k]))
+            return (up_locks[k], 1 - f, k, math.ceil(values[k]))
+
+        best = min(score(k) for k in candidates)
+        return best[2], float(best[3])
+
+    j = min(candidates, key=lambda k: min(frac(k), 1 - frac(k)))
+    return j, float(round(values[j]))
+
+
+def dive(model: FacilityLocationModel, mkp: cplex.Cplex, rule='fractional', incumbent=None,
+         time_limit=2.0, max_depth=None, tolerance=1e-6, clock=WALL_CLOCK):
+    """
+    Euristica di diving: fissa una facility alla volta lungo un cammino di "tuffo"
+    risolvendo di nuovo il rilassamento LP (su una copia di mkp), finché tutte le x_u sono intere.
+    Le y_uv vengono poi ricavate assegnando ogni cliente alla facility aperta più economica.
+    Restituisce un dizionario {'objective', 'open_facilities', 'values'} oppure None.
+    Il limite di tempo è misurato con clock (utility.clock), a cui vengono addebitate le iterazioni LP.
+    """
+    start = clock.now()
+    p = model.get_num_facilities()
+    facility_cols = [model.variable_index(u) for u in range(p)]
+
+    incumbent_values = None
+    if incumbent is not None:
+        incumbent_values = [incumbent['values'].get(f"x{j}", 0.0) for j in range(model.get_num_variables())]
+
+    with cplex.Cplex(mkp) as lp:
+        lp.set_log_stream(None)
+        lp.set_error_stream(None)
+        lp.set_warning_stream(None)
+        lp.set_results_stream(None)
+
+        def solve():
+            lp.solve()
+            clock.charge(1 + lp.solution.progress.get_num_iterations())
+
+        # La copia non porta con sé la soluzione: si riparte dal rilassamento corrente
+        solve()
+        if lp.solution.get_status() != lp.solution.status.optimal:
+            return None
+
+        locks = _variable_locks(lp, model.get_num_variables()) if rule == 'coefficient' else None
+        depth = 0
+        while True:
+            if clock.now() - start > time_limit or (max_depth is not None and depth >= max_depth):
+                return None
+
+            values = lp.solution.get_values()
+            candidates = [j for j in facility_cols
+                          if tolerance < values[j] - math.floor(values[j]) < 1 - tolerance]
+            if not candidates:
+                break
+
+            j, fixed_value = _select_dive_variable(rule, candidates, values, locks, incumbent_values)
+            lp.variables.set_lower_bounds(j, fixed_value)
+            lp.variables.set_upper_bounds(j, fixed_value)
+            solve()
+
+            if lp.solution.get_status() != lp.solution.status.optimal:
+                # Un solo tentativo di backtracking: si prova la direzione opposta
+                other_value = 1.0 - fixed_value
+                lp.variables.set_lower_bounds(j, other_value)
+                lp.variables.set_upper_bounds(j, other_value)
+                solve()
+                if lp.solution.get_status() != lp.solution.status.optimal:
+                    return None
+            depth += 1
+
+        open_facilities = [u for u in range(p) if values[facility_cols[u]] > 0.5]
+
+    if not open_facilities:
+        return None
+    return build_candidate(model, open_facilities)
+
+
+@dataclass
+class HeuristicContext:
+    """
+    Stato del solve passato alle euristiche dell'utente:
+    lp_values contiene i valori LP delle sole variabili originali (indice = colonna del modello),
+    incumbent è il miglior candidato finora ({'objective', 'values', ...}) o None,
+    relaxation è il problema CPLEX corrente, da non modificare.
+    """
+    model: FacilityLocationModel
+    lp_values: list
+    lp_objective: float
+    incumbent: dict
+    iteration: int
+    relaxation: cplex.Cplex
+
+
+class Heuristic:
+    """
+    Interfaccia per euristiche primali definite dall'utente (vedi Gomory.register_heuristic).
+    run(context) restituisce None oppure un candidato in una di queste forme:
+    - un insieme di facility da aprire: i clienti vengono assegnati alla più economica;
+    - un dizionario {'values': nome variabile -> valore}, verificato vincolo per vincolo.
+    """
+    name = None
+
+    def run(self, context: HeuristicContext):
+        raise NotImplementedError
+
+
+class FunctionHeuristic(Heuristic):
+    """Adatta una funzione context -> candidato all'interfaccia Heuristic."""
+    def __init__(self, name, function):
+        self.name = name
+        self.function = function
+
+    def run(self, context: HeuristicContext):
+        return self.function(context)
+
+
+def candidate_from_result(model: FacilityLocationModel, result, tolerance=1e-6):
+    """Converte il risultato di un'euristica utente in candidato; solleva ValueError se non è ammissibile."""
+    if result is None:
+        return None
+    if not isinstance(result, dict):
+        open_facilities = sorted(set(result))
+        if not open_facilities:
+            return None
+        unknown = [u for u in open_facilities if not 0 <= u < model.get_num_facilities()]
+        if unknown:
+            raise ValueError(f"facility inesistenti nel candidato: {unknown}")
+        return build_candidate(model, open_facilities)
+
+    values = {f"x{i}": float(result['values'].get(f"x{i}", 0.0)) for i in range(model.get_num_variables())}
+    report = check_solution(model, values, tolerance)
+    if not report['feasible']:
+        raise ValueError(f"candidato non ammissibile: {len(report['violated_constraints'])} vincoli e "
+                         f"{len(report['violated_variables'])} variabili violati")
+    fixed_costs, assignment_costs = model.get_fixed_costs(), model.get_assignment_costs()
+    p, r = model.get_num_facilities(), model.get_num_customers()
+    open_facilities = [u for u in range(p) if values[f"x{u}"] > 0.5]
+    objective = sum(float(fixed_costs[u]) for u in open_facilities)
+    objective += sum(float(assignment_costs[v][u]) * values[f"x{model.variable_index(u, v)}"]
+                     for u in range(p) for v in range(r))
+    return {'objective': objective, 'open_facilities': open_facilities, 'values': values}
+
+
+class HeuristicScheduler:
+    """
+    Decide quando eseguire ciascuna euristica in base al suo storico:
+    ogni euristica viene eseguita una volta ogni `frequency` occasioni; la frequenza
+    raddoppia dopo ogni chiamata senza miglioramento (o troppo costosa) e torna a 1 dopo un successo.
+    Le euristiche in `forced` girano sempre, quelle in `disabled` mai.
+    """
+    def __init__(self, heuristics: dict, forced=(), disabled=(), max_frequency=16, slow_threshold=1.0,
+                 clock=WALL_CLOCK):
+        unknown = (set(forced) | set(disabled)) - set(heuristics)
+        if unknown:
+            raise ValueError(f"Euristiche sconosciute: {sorted(unknown)}. Disponibili: {sorted(heuristics)}")
+
+        self.heuristics = heuristics
+        self.forced = set(forced)
+        self.disabled = set(disabled)
+        self.max_frequency = max_frequency
+        # Oltre questo tempo medio (secondi) un'euristica viene considerata costosa
+        self.slow_threshold = slow_threshold
+        self.clock = clock
+        self.stats = {name: {'calls': 0, 'successes': 0, 'skipped': 0, 'total_time': 0.0,
+                             'frequency': 1, 'opportunities': 0}
+                      for name in heuristics}
+
+    def should_run(self, name):
+        """Aggiorna il contatore di occasioni e dice se l'euristica va eseguita ora."""
+        if name in self.disabled:
+            return False
+        stats = self.stats[name]
+        stats['opportunities'] += 1
+        if name in self.forced or stats['opportunities'] >= stats['frequency']:
+            stats['opportunities'] = 0
+            return True
+        stats['skipped'] += 1
+        return False
+
+    def record(self, name, elapsed, improved):
+        """Registra l'esito di una chiamata e adatta la frequenza dell'euristica."""
+        stats = self.stats[name]
+        stats['calls'] += 1
+        stats['total_time'] += elapsed
+        if improved:
+            stats['successes'] += 1
+            stats['frequency'] = 1
+            return
+
+        average_time = stats['total_time'] / stats['calls']
+        growth = 4 if average_time > self.slow_threshold else 2
+        stats['frequency'] = min(self.max_frequency, stats['frequency'] * growth)
+
+    def run(self, mkp, on_candidate, on_failure):
+        """
+        Esegue le euristiche selezionate sulla soluzione LP corrente.
+        on_candidate(nome, candidato) deve restituire True se il candidato migliora l'incumbent;
+        on_failure(nome, eccezione) viene chiamata se un'euristica fallisce.
+        """
+        for name, heuristic in self.heuristics.items():
+            if not self.should_run(name):
+                continue
+            start = self.clock.now()
+            try:
+                candidate = heuristic(mkp)
+            except Exception as e:
+                self.record(name, self.clock.now() - start, improved=False)
+                on_failure(name, e)
+                continue
+            improved = bool(candidate) and on_candidate(name, candidate)
+            self.record(name, self.clock.now() - start, improved)
+
+    def summary(self):
+        """Tasso di successo e costo medio di ciascuna euristica."""
+        return {
+            name: {
+                'calls': s['calls'],
+                'skipped': s['skipped'],
+                'success_rate': s['successes'] / s['calls'] if s['calls'] else 0.0,
+                'average_time': s['total_time'] / s['calls'] if s['calls'] else 0.0,
+            }
+            for name, s in self.stats.items()
+        }
@@ -0,0 +1,321 @@
+from configparser import ConfigParser, Error as ConfigParserError
+from dataclasses import dataclass, field, fields, replace
+from pathlib import Path
+
+from config import *
+from algorithm.strategy import LP_METHODS
+from algorithm.branching import BRANCHING_RULES, NODE_SELECTIONS, BranchingStrategy
+from utility.errors import OptionsError
+
+# parametri del solver: costruzione con opzioni funzionali o da file .ini (sezione [SOLVER])
+
+CUT_MODES = ('GFC', 'GMI', 'BEST', 'MIR', 'ZH')
+HEURISTIC_NAMES = ('rounding_repair', 'dive_fractional', 'dive_coefficient', 'dive_guided')
+
+
+@dataclass
+class SolverOptions:
+    """
+    Insieme completo dei parametri del ciclo dei piani di taglio (default da config.py).
+    cut_mode = 'AUTO' e lp_method = 'auto' lasciano la scelta a algorithm.strategy.select_strategy.
+    """
+    cut_mode: str = 'GMI'
+    lp_method: str = 'auto'
+    time_limit: float = TIME_LIMIT
+    max_iterations: int = MAX_ITERATIONS
+    threshold_gap: float = THRESHOLD_GAP
+    cut_min_efficacy: float = CUT_MIN_EFFICACY
+    cut_max_age: int = CUT_MAX_AGE
+    reduced_cost_fixing: bool = REDUCED_COST_FIXING
+    exact_presolve: bool = EXACT_PRESOLVE
+    gub_branching: bool = GUB_BRANCHING
+    branching_rule: str = BRANCHING_RULE
+    strong_candidates: int = STRONG_CANDIDATES
+    strong_iterations: int = STRONG_ITERATIONS
+    node_selection: str = NODE_SELECTION
+    node_backtrack: float = NODE_BACKTRACK
+    diving_time_limit: float = DIVING_TIME_LIMIT
+    presolve_time_limit: float = PRESOLVE_TIME_LIMIT
+    root_cut_time: float = ROOT_CUT_TIME
+    tree_time_limit: float = TREE_TIME_LIMIT
+    heuristic_time_share: float = HEURISTIC_TIME_SHARE
+    heuristics_forced: list = field(default_factory=lambda: list(HEURISTICS_FORCED))
+    heuristics_disabled: list = field(default_factory=lambda: list(HEURISTICS_DISABLED))
+    # Verifica delle soluzioni vincolo per vincolo (violazioni rispetto alla tolleranza)
+    debug: bool = DEBUG_CHECK_SOLUTION
+    # Registra ogni solve (riepilogo, parametri e tempi per fase) nell'archivio storico HISTORY_DB
+    record_history: bool = RECORD_HISTORY
+    # Profili CPU e heap di ogni solve (vedi utility.profiling.SolveProfiler)
+    profile_cpu: bool = PROFILE_CPU
+    profile_heap: bool = PROFILE_HEAP
+
+    def validate(self):
+        """Controlla tutti i parametri e solleva OptionsError con l'elenco completo dei problemi."""
+        problems = []
+        self.cut_mode = self.cut_mode.upper()
+        self.lp_method = self.lp_method.lower()
+        if self.cut_mode not in CUT_MODES + ('AUTO',):
+            problems.append(f"cut_mode '{self.cut_mode}' non valida: scegliere tra {', '.join(CUT_MODES)} o AUTO")
+        if self.lp_method not in ('auto',) + LP_METHODS:
+            problems.append(f"lp_method '{self.lp_method}' non valido: scegliere tra auto, {', '.join(LP_METHODS)}")
+        self.branching_rule = self.branching_rule.lower()
+        if self.branching_rule not in BRANCHING_RULES:
+            problems.append(f"branching_rule '{self.branching_rule}' non valida: scegliere tra {', '.join(BRANCHING_RULES)}")
+        if self.strong_candidates < 1:
+            problems.append(f"strong_candidates deve essere almeno 1 (trovato {self.strong_candidates})")
+        if self.strong_iterations < 0:
+            problems.append(f"strong_iterations non può essere negativo (trovato {self.strong_iterations})")
+        self.node_selection = self.node_selection.lower()
+        if self.node_selection not in NODE_SELECTIONS:
+            problems.append(f"node_selection '{self.node_selection}' non valida: scegliere tra {', '.join(NODE_SELECTIONS)}")
+        if self.node_backtrack < 0:
+            problems.append(f"node_backtrack non può essere negativo (trovato {self.node_backtrack})")
+        if self.time_limit <= 0:
+            problems.append(f"time_limit deve essere positivo (trovato {self.time_limit})")
+        if self.max_iterations < 0:
+            problems.append(f"max_iterations non può essere negativo (trovato {self.max_iterations})")
+        if not 0 <= self.threshold_gap < 1:
+            problems.append(f"threshold_gap deve essere in [0, 1) (trovato {self.threshold_gap})")
+        if self.cut_min_efficacy < 0:
+            problems.append(f"cut_min_efficacy non può essere negativo (trovato {self.cut_min_efficacy})")
+        if self.cut_max_age < 1:
+            problems.append(f"cut_max_age deve essere almeno 1 (trovato {self.cut_max_age})")
+        if self.diving_time_limit <= 0:
+            problems.append(f"diving_time_limit deve essere positivo (trovato {self.diving_time_limit})")
+        for key in ('presolve_time_limit', 'root_cut_time', 'tree_time_limit'):
+            if getattr(self, key) < 0:
+                problems.append(f"{key} non può essere negativo (trovato {getattr(self, key)}; 0 = nessun limite)")
+        if not 0 < self.heuristic_time_share <= 1:
+            problems.append(f"heuristic_time_share deve essere in (0, 1] (trovato {self.heuristic_time_share})")
+        for key in ('heuristics_forced', 'heuristics_disabled'):
+            unknown = sorted(set(getattr(self, key)) - set(HEURISTIC_NAMES))
+            if unknown:
+                problems.append(f"{key}: euristiche sconosciute {unknown} (disponibili: {', '.join(HEURISTIC_NAMES)})")
+        both = sorted(set(self.heuristics_forced) & set(self.heuristics_disabled))
+        if both:
+            problems.append(f"euristiche sia forzate che disabilitate: {both}")
+
+        if problems:
+            raise OptionsError("Parametri del solver non validi:\n  - " + "\n  - ".join(problems), problems)
+        return self
+
+    def branching_strategy(self):
+        return BranchingStrategy(self.branching_rule, self.strong_candidates, self.strong_iterations,
+                                 self.node_selection, self.node_backtrack)
+
+
+# --- Opzioni funzionali: ciascuna restituisce una funzione che modifica SolverOptions ---
+
+def with_time_limit(seconds):
+    def apply(options):
+        options.time_limit = seconds
+    return apply
+
+
+def with_phase_budgets(presolve=None, root_cuts=None, tree=None, heuristic_share=None):
+    """Budget di tempo per fase in secondi (0 = nessun limite) e quota di tempo delle euristiche."""
+    def apply(options):
+        if presolve is not None:
+            options.presolve_time_limit = presolve
+        if root_cuts is not None:
+            options.root_cut_time = root_cuts
+        if tree is not None:
+            options.tree_time_limit = tree
+        if heuristic_share is not None:
+            options.heuristic_time_share = heuristic_share
+    return apply
+
+
+def with_cuts(cut_mode):
+    def apply(options):
+        options.cut_mode = cut_mode
+    return apply
+
+
+def with_lp_method(lp_method):
+    def apply(options):
+        options.lp_method = lp_method
+    return apply
+
+
+def with_max_iterations(max_iterations):
+    def apply(options):
+        options.max_iterations = max_iterations
+    return apply
+
+
+def with_gap(threshold_gap):
+    def apply(options):
+        options.threshold_gap = threshold_gap
+    return apply
+
+
+def with_cut_pool(min_efficacy=None, max_age=None):
+    def apply(options):
+        if min_efficacy is not None:
+            options.cut_min_efficacy = min_efficacy
+        if max_age is not None:
+            options.cut_max_age = max_age
+    return apply
+
+
+def with_heuristics(forced=None, disabled=None):
+    def apply(options):
+        if forced is not None:
+            options.heuristics_forced = list(forced)
+        if disabled is not None:
+            options.heuristics_disabled = list(disabled)
+    return apply
+
+
+def with_reduced_cost_fixing(enabled=True):
+    def apply(options):
+        options.reduced_cost_fixing = enabled
+    return apply
+
+
+def with_exact_presolve(enabled=True):
+    def apply(options):
+        options.exact_presolve = enabled
+    return apply
+
+
+def with_gub_branching(enabled=True):
+    def apply(options):
+        options.gub_branching = enabled
+    return apply
+
+
+def with_branching(rule, strong_candidates=None, strong_iterations=None):
+    def apply(options):
+        options.branching_rule = rule
+        if strong_candidates is not None:
+            options.strong_candidates = strong_candidates
+        if strong_iterations is not None:
+            options.strong_iterations = strong_iterations
+    return apply
+
+
+def with_node_selection(node_selection, backtrack=None):
+    def apply(options):
+        options.node_selection = node_selection
+        if backtrack is not None:
+            options.node_backtrack = backtrack
+    return apply
+
+
+def with_debug(enabled=True):
+    def apply(options):
+        options.debug = enabled
+    return apply
+
+
+def with_history(enabled=True):
+    def apply(options):
+        options.record_history = enabled
+    return apply
+
+
+def with_profiling(cpu=True, heap=True):
+    def apply(options):
+        options.profile_cpu = cpu
+        options.profile_heap = heap
+    return apply
+
+
+def build_options(*opts, base: SolverOptions = None):
+    """Applica le opzioni funzionali (in ordine) a una copia di base e valida il risultato."""
+    options = replace(base) if base is not None else SolverOptions()
+    for opt in opts:
+        opt(options)
+    return options.validate()
+
+
+# --- Caricamento da file ---
+
+def _parse_value(kind, raw: str):
+    if kind is bool:
+        value = raw.strip().lower()
+        if value in ('1', 'true', 'yes', 'on', 'si', 'sì'):
+            return True
+        if value in ('0', 'false', 'no', 'off'):
+            return False
+        raise ValueError(f"valore booleano non riconosciuto '{raw}'")
+    if kind is list:
+        return [item.strip() for item in raw.split(',') if item.strip()]
+    if kind is str:
+        return raw.strip()
+    return kind(raw)
+
+
+def load_options(config_file, section='SOLVER', base: SolverOptions = None):
+    """
+    Legge i parametri del solver dalla sezione indicata di un file .ini, es.:
+
+        [SOLVER]
+        CUT_MODE = BEST
+        TIME_LIMIT = 600
+        HEURISTICS_DISABLED = dive_coefficient, dive_guided
+
+    Le chiavi non indicate mantengono il valore di base (default: SolverOptions()).
+    Chiavi sconosciute, valori non convertibili e valori fuori range vengono riportati
+    tutti insieme in un unico OptionsError.
+    """
+    config_file = Path(config_file)
+    parser = ConfigParser()
+    try:
+        if not parser.read(config_file):
+            raise OptionsError(f"File di configurazione '{config_file}' non trovato o non leggibile.")
+    except ConfigParserError as e:
+        raise OptionsError(f"File di configurazione '{config_file}' non valido: {e}") from e
+    if not parser.has_section(section):
+        raise OptionsError(f"Sezione [{section}] mancante in '{config_file}'.")
+
+    options = replace(base) if base is not None else SolverOptions()
+    kinds = {f.name: (bool if f.type in (bool, 'bool') else
+                      list if f.type in (list, 'list') else
+                      int if f.type in (int, 'int') else
+                      float if f.type in (float, 'float') else str)
+             for f in fields(SolverOptions)}
+
+    problems = []
+    for key, raw in parser[section].items():
+        name = key.lower()
+        if name not in kinds:
+            problems.append(f"chiave sconosciuta '{key.upper()}' (valide: {', '.join(k.upper() for k in kinds)})")
+            continue
+        try:
+            setattr(options, name, _parse_value(kinds[name], raw))
+        except ValueError as e:
+            problems.append(f"{key.upper()}: {e}")
+
+    try:
+        options.validate()
+    except OptionsError as e:
+        problems += e.details
+    if problems:
+        raise OptionsError(f"Errori nel file di configurazione '{config_file}':\n  - " + "\n  - ".join(problems),
+                           problems)
+    return options
+
+
+def write_options(options: SolverOptions, config_file, section='SOLVER', comment=None):
+    """Scrive i parametri in un file .ini leggibile da load_options."""
+    parser = ConfigParser()
+    parser.optionxform = str  # mantiene le chiavi in maiuscolo, come nei file scritti a mano
+    parser[section] = {}
+    for f in fields(SolverOptions):
+        value = getattr(options, f.name)
+        if isinstance(value, list):
+            value = ', '.join(value)
+        elif isinstance(value, bool):
+            value = 'true' if value else 'false'
+        parser[section][f.name.upper()] = str(value)
+
+    config_file = Path(config_file)
+    config_file.parent.mkdir(parents=True, exist_ok=True)
+    with open(config_file, 'w') as f:
+        if comment:
+            f.write("".join(f"# {line}\n" for line in comment.splitlines()) + "\n")
+        parser.write(f)
+    return config_file
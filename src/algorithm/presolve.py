@@ -0,0 +1,273 @@
+from dataclasses import dataclass, field
+from decimal import Decimal
+from fractions import Fraction
+
+import numpy as np
+
+from utility.errors import InfeasibleError
+from utility.clock import WALL_CLOCK
+from utility.facilityLocation import FacilityLocationModel
+
+# presolve in aritmetica esatta (frazioni) sui vincoli nella forma a x <= b, prima della conversione in floating point
+
+
+def exact_value(value):
+    """
+    Converte un coefficiente in Fraction senza perdita: accetta int, Fraction, Decimal
+    e stringhe decimali o razionali ("0.1", "1e-3", "3/7"). I float vengono letti dalla
+    loro rappresentazione decimale (0.1 -> 1/10), non dal valore binario.
+    """
+    if isinstance(value, (Fraction, int)):
+        return Fraction(value)
+    if isinstance(value, float):
+        return Fraction(repr(value))
+    if isinstance(value, Decimal):
+        return Fraction(value)
+    return Fraction(str(value).strip())
+
+
+def exact_problem_rows(model: FacilityLocationModel):
+    """Righe sparse {indice: Fraction} e rhs esatti, nello stesso ordine di Solver.get_problem_data."""
+    p, r = model.get_num_facilities(), model.get_num_customers()
+    rows, rhs = [], []
+    for v in range(r):
+        row = {p + u * r + v: Fraction(1) for u in range(p)}
+        rows += [row, {j: -a for j, a in row.items()}]
+        rhs += [Fraction(1), Fraction(-1)]
+    for u in range(p):
+        for v in range(r):
+            rows.append({p + u * r + v: Fraction(1), u: Fraction(-1)})
+            rhs.append(Fraction(0))
+    for constraint in model.side_constraints:
+        row = {j: exact_value(a) for j, a in zip(constraint['indices'], constraint['coeffs'])}
+        if constraint['sense'] in ('L', 'E'):
+            rows.append(row)
+            rhs.append(exact_value(constraint['rhs']))
+        if constraint['sense'] in ('G', 'E'):
+            rows.append({j: -a for j, a in row.items()})
+            rhs.append(-exact_value(constraint['rhs']))
+    return rows, rhs
+
+
+def exact_objective(model: FacilityLocationModel):
+    p, r = model.get_num_facilities(), model.get_num_customers()
+    c = [exact_value(cost) for cost in model.get_fixed_costs()] + [Fraction(0)] * (p * r)
+    assignment_costs = model.get_assignment_costs()
+    for u in range(p):
+        for v in range(r):
+            c[p + u * r + v] = exact_value(assignment_costs[v][u])
+    return c
+
+
+@dataclass
+class PresolveResult:
+    """
+    Problema ridotto, già convertito in floating point (A densa come in get_problem_data).
+    kept_rows[i] / kept_columns[j] sono gli indici nel problema originale della riga i / colonna j.
+    merged_columns[j] elenca le colonne originali aggregate nella colonna ridotta j, con i loro
+    bound, per ridistribuirne il valore in postsolve_values.
+    """
+    c: np.ndarray
+    A: np.ndarray
+    b: np.ndarray
+    lb: list
+    ub: list
+    kept_rows: list
+    n_rows_original: int
+    kept_columns: list = None
+    n_cols_original: int = None
+    merged_columns: dict = field(default_factory=dict)
+    removed: dict = field(default_factory=dict)
+    # False se il presolve si è fermato per limite di tempo (il ridotto resta valido, solo meno semplificato)
+    complete: bool = True
+
+    def postsolve_duals(self, duals):
+        """Duali del problema originale: le righe eliminate (vuote, singoletto, ridondanti, parallele) hanno duale nullo."""
+        full = [0.0] * self.n_rows_original
+        for i, original in enumerate(self.kept_rows):
+            full[original] = duals[i]
+        return full
+
+    def postsolve_values(self, values):
+        """
+        Soluzione primale del problema originale dai valori delle colonne ridotte.
+        Il valore di una colonna aggregata viene ripartito tra le originali partendo dai
+        lower bound e riempiendo una colonna alla volta fino all'upper bound.
+        """
+        full = [0.0] * self.n_cols_original
+        for j, original in enumerate(self.kept_columns):
+            group = self.merged_columns.get(j)
+            if group is None:
+                full[original] = values[j]
+                continue
+            remaining = values[j] - sum(lb for _, lb, _ in group)
+            for k, lb, ub in group:
+                share = min(ub - lb, max(remaining, 0.0))
+                full[k] = lb + share
+                remaining -= share
+        return full
+
+
+def _floor(value: Fraction):
+    return Fraction(value.numerator // value.denominator)
+
+
+def _ceil(value: Fraction):
+    return -_floor(-value)
+
+
+def _row_key(row):
+    """Riga normalizzata (primo coefficiente di modulo 1) e fattore di scala: righe parallele hanno la stessa chiave."""
+    scale = abs(row[min(row)])
+    return tuple(sorted((j, a / scale) for j, a in row.items())), scale
+
+
+def _parallel_rows(rows, rhs, active, removed):
+    """
+    Tra righe parallele con lo stesso verso (a x <= b1, k a x <= b2 con k > 0) tiene solo la più stringente;
+    righe opposte (a x <= b1, -a x <= b2) restano entrambe, ma se -b2 > b1 il problema è inammissibile.
+    """
+    best, changed = {}, False
+    for i, row in enumerate(rows):
+        if not active[i] or len(row) < 2:
+            continue
+        key, scale = _row_key(row)
+        normalized = rhs[i] / scale
+        if key in best:
+            k, other = best[key]
+            if normalized < other:
+                active[k] = False
+                best[key] = (i, normalized)
+            else:
+                active[i] = False
+            removed['parallel_rows'] += 1
+            changed = True
+        else:
+            best[key] = (i, normalized)
+
+    for key, (i, normalized) in best.items():
+        opposite = best.get(tuple((j, -a) for j, a in key))
+        if opposite is not None and -opposite[1] > normalized:
+            raise InfeasibleError(f"Righe opposte {i} e {opposite[0]} incompatibili: problema inammissibile.")
+    return changed
+
+
+def _duplicate_columns(c, rows, active, integer_mask):
+    """Gruppi di colonne con lo stesso costo, lo stesso tipo e gli stessi coefficienti su tutte le righe attive."""
+    columns = {j: [] for j in range(len(c))}
+    for i, row in enumerate(rows):
+        if active[i]:
+            for j, a in row.items():
+                columns[j].append((i, a))
+    groups = {}
+    for j, column in columns.items():
+        groups.setdefault((tuple(column), c[j], integer_mask[j]), []).append(j)
+    return [group for group in groups.values() if len(group) > 1]
+
+
+def exact_presolve(c, rows, rhs, lb, ub, integer_mask=None, aggregate_columns=False, max_passes=10,
+                   time_limit=0, clock=WALL_CLOCK):
+    """
+    Semplifica in aritmetica esatta il problema min c x, rows[i] x <= rhs[i], lb <= x <= ub:
+    - righe vuote (eliminate, o inammissibilità se rhs < 0);
+    - righe singoletto a x_j <= b, trasformate in bound su x_j (arrotondati se x_j è intera);
+    - righe ridondanti, la cui attività massima sui bound non supera rhs;
+    - righe duplicate o parallele (multiple positive l'una dell'altra): resta la più stringente;
+    - con aggregate_columns=True, colonne duplicate (stessi costo, tipo e coefficienti) sommate
+      in un'unica colonna con bound [sum lb, sum ub]; postsolve_values ne ricostruisce i valori.
+    Coefficienti quasi uguali restano distinti: nessuna tolleranza entra nelle decisioni.
+    Solleva InfeasibleError se una riga o un bound risultano inammissibili.
+    Con time_limit > 0 (secondi, misurati con clock) non inizia nuovi passaggi oltre il limite.
+    """
+    start = clock.now()
+    complete = True
+    n = len(c)
+    integer_mask = integer_mask if integer_mask is not None else [True] * n
+    c = [exact_value(v) for v in c]
+    lb, ub = [exact_value(v) for v in lb], [exact_value(v) for v in ub]
+    rows = [{j: exact_value(a) for j, a in row.items() if a != 0} for row in rows]
+    rhs = [exact_value(v) for v in rhs]
+    active = [True] * len(rows)
+    removed = {'empty_rows': 0, 'singleton_rows': 0, 'redundant_rows': 0, 'parallel_rows': 0,
+               'tightened_bounds': 0, 'merged_columns': 0}
+
+    for presolve_pass in range(max_passes):
+        if presolve_pass > 0 and time_limit and clock.now() - start > time_limit:
+            complete = False
+            break
+        changed = False
+        for i, row in enumerate(rows):
+            if not active[i]:
+                continue
+            if not row:
+                if rhs[i] < 0:
+                    raise InfeasibleError(f"Riga {i} vuota con rhs {rhs[i]} < 0: problema inammissibile.")
+                active[i] = False
+                removed['empty_rows'] += 1
+                changed = True
+            elif len(row) == 1:
+                (j, a), = row.items()
+                bound = rhs[i] / a
+                if a > 0:
+                    bound = _floor(bound) if integer_mask[j] else bound
+                    if bound < ub[j]:
+                        ub[j] = bound
+                        removed['tightened_bounds'] += 1
+                else:
+                    bound = _ceil(bound) if integer_mask[j] else bound
+                    if bound > lb[j]:
+                        lb[j] = bound
+                        removed['tightened_bounds'] += 1
+                if lb[j] > ub[j]:
+                    raise InfeasibleError(f"Bound incompatibili per x{j}: [{lb[j]}, {ub[j]}] (riga {i}).")
+                active[i] = False
+                removed['singleton_rows'] += 1
+                changed = True
+            elif sum(a * (ub[j] if a > 0 else lb[j]) for j, a in row.items()) <= rhs[i]:
+                active[i] = False
+                removed['redundant_rows'] += 1
+                changed = True
+        changed = _parallel_rows(rows, rhs, active, removed) or changed
+        if not changed:
+            break
+
+    # Colonne duplicate: la prima del gruppo rappresenta la somma, le altre spariscono
+    representative = {j: j for j in range(n)}
+    merged = {}
+    if aggregate_columns:
+        for group in _duplicate_columns(c, rows, active, integer_mask):
+            merged[group[0]] = [(k, lb[k], ub[k]) for k in group]
+            for k in group[1:]:
+                representative[k] = group[0]
+            lb[group[0]], ub[group[0]] = sum(lb[k] for k in group), sum(ub[k] for k in group)
+            removed['merged_columns'] += len(group) - 1
+
+    kept_rows = [i for i in range(len(rows)) if active[i]]
+    kept_columns = [j for j in range(n) if representative[j] == j]
+    position = {j: k for k, j in enumerate(kept_columns)}
+    A = np.zeros((len(kept_rows), len(kept_columns)), dtype=np.float64)
+    for k, i in enumerate(kept_rows):
+        for j, a in rows[i].items():
+            if representative[j] == j:
+                A[k, position[j]] = float(a)
+    return PresolveResult(
+        c=np.array([float(c[j]) for j in kept_columns], dtype=np.float64), A=A,
+        b=np.array([float(rhs[i]) for i in kept_rows], dtype=np.float64),
+        lb=[float(lb[j]) for j in kept_columns], ub=[float(ub[j]) for j in kept_columns],
+        kept_rows=kept_rows, n_rows_original=len(rows),
+        kept_columns=kept_columns, n_cols_original=n,
+        merged_columns={position[j]: [(k, float(l), float(u)) for k, l, u in group] for j, group in merged.items()},
+        removed=removed, complete=complete
+    )
+
+
+def presolve_model(model: FacilityLocationModel, time_limit=0, clock=WALL_CLOCK):
+    """
+    Presolve esatto del rilassamento di un modello UFL (variabili binarie in [0, 1]), usato da
+    Gomory.solve_problem: rimuove righe e restringe bound, ma non aggrega colonne, perché euristiche,
+    pool dei tagli e reduced-cost fixing lavorano sulle variabili originali x{i}. Per questo il
+    solve non chiama postsolve_values/postsolve_duals, che servono solo con aggregate_columns=True.
+    """
+    n = model.get_num_variables()
+    rows, rhs = exact_problem_rows(model)
+    return exact_presolve(exact_objective(model), rows, rhs, [0] * n, [1] * n, time_limit=time_limit, clock=clock)
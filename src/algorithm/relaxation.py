@@ -0,0 +1,126 @@
+from contextlib import contextmanager
+
+import cplex
+from algorithm.solver import Solver
+from utility.errors import SolveStatus, SolverError, status_from_cplex
+from utility.facilityLocation import FacilityLocationModel
+
+# accesso di basso livello al rilassamento LP, per chi costruisce la propria ricerca ad albero o un ciclo di Benders
+
+
+class Relaxation:
+    """
+    Rilassamento LP del modello UFL mantenuto in memoria tra una risoluzione e l'altra.
+    Dopo una modifica dei bound, resolve() riottimizza con il simplesso duale partendo
+    dalla base precedente (la base resta duale ammissibile quando cambiano solo i bound).
+    Uso tipico:
+
+        with Relaxation(model) as lp:
+            lp.resolve()
+            lp.change_bounds({3: (1.0, 1.0)}).resolve()
+    """
+    def __init__(self, model: FacilityLocationModel):
+        self.model = model
+        solver = Solver(model)
+        c = solver.get_costs(maximize=False)
+        self.n_cols = len(c)
+        self.status = None
+
+        self.lp = cplex.Cplex()
+        self.lp.set_log_stream(None)
+        self.lp.set_error_stream(None)
+        self.lp.set_warning_stream(None)
+        self.lp.set_results_stream(None)
+        self.lp.set_problem_type(self.lp.problem_type.LP)
+        self.lp.objective.set_sense(self.lp.objective.sense.minimize)
+        # Senza presolve la base resta riferita al modello originale e può essere riutilizzata
+        self.lp.parameters.preprocessing.presolve.set(0)
+        self.lp.parameters.lpmethod.set(self.lp.parameters.lpmethod.values.dual)
+
+        self.lp.variables.add(obj=c.tolist(), lb=[0.0] * self.n_cols, ub=[1.0] * self.n_cols,
+                              names=[f"x{i}" for i in range(self.n_cols)])
+        solver.add_problem_rows(self.lp)
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    def close(self):
+        self.lp.end()
+
+    def change_bounds(self, changes):
+        """
+        Applica modifiche ai bound delle variabili e restituisce la stessa Relaxation (per concatenare resolve()).
+        changes: dizionario indice -> (lb, ub), dove None lascia invariato il bound corrispondente.
+        """
+        lower, upper = [], []
+        for index, (lb, ub) in changes.items():
+            if not 0 <= index < self.n_cols:
+                raise IndexError(f"Variabile {index} fuori dal modello ({self.n_cols} variabili).")
+            if lb is not None:
+                lower.append((index, float(lb)))
+            if ub is not None:
+                upper.append((index, float(ub)))
+        if lower:
+            self.lp.variables.set_lower_bounds(lower)
+        if upper:
+            self.lp.variables.set_upper_bounds(upper)
+        return self
+
+    @contextmanager
+    def fixing(self, fixings):
+        """
+        Fissa temporaneamente alcune variabili (indice o nome -> valore) e ripristina i bound all'uscita:
+
+            with lp.fixing({'open[2]': 0}):
+                value, status = lp.resolve()
+        """
+        fixings = self.model.fixing_indices(fixings)
+        previous = {index: self.get_bounds(index) for index in fixings}
+        self.change_bounds({index: (value, value) for index, value in fixings.items()})
+        try:
+            yield self
+        finally:
+            self.change_bounds(previous)
+
+    def get_bounds(self, index):
+        return self.lp.variables.get_lower_bounds(index), self.lp.variables.get_upper_bounds(index)
+
+    def resolve(self):
+        """
+        Riottimizza col simplesso duale dalla base corrente.
+        Restituisce (valore obiettivo o None, SolveStatus).
+        """
+        try:
+            self.lp.solve()
+        except cplex.CplexError as e:
+            self.status = SolveStatus.ERROR
+            raise SolverError(f"Errore CPLEX nel rilassamento: {e}") from e
+        self.status = status_from_cplex(self.lp.solution.get_status())
+        if not self.status.has_solution:
+            return None, self.status
+        return self.lp.solution.get_objective_value(), self.status
+
+    def iterations(self):
+        """Iterazioni del simplesso dell'ultima risoluzione (utile per misurare il warm start)."""
+        return self.lp.solution.progress.get_num_iterations()
+
+    def values(self):
+        return self.lp.solution.get_values()
+
+    def reduced_costs(self):
+        return self.lp.solution.get_reduced_costs()
+
+    def duals(self):
+        return self.lp.solution.get_dual_values()
+
+    def get_basis(self):
+        """Istantanea della base corrente (stati di colonne e righe), da ripristinare con set_basis."""
+        return self.lp.solution.basis.get_basis()
+
+    def set_basis(self, basis):
+        col_status, row_status = basis
+        self.lp.start.set_start(col_status=col_status, row_status=row_status,
+                                col_primal=[], row_primal=[], col_dual=[], row_dual=[])
@@ -0,0 +1,175 @@
+from concurrent.futures import ThreadPoolExecutor
+from dataclasses import dataclass, field
+
+import cplex
+from algorithm.solver import Solver
+from utility.clock import WALL_CLOCK
+from utility.errors import SolveStatus, status_from_cplex
+
+# riottimizzazione dello stesso modello su molti scenari di costi e termini noti (analisi di rischio)
+
+
+@dataclass
+class Scenario:
+    """
+    Variante del modello: objective sostituisce dei costi (indice o nome di variabile -> costo),
+    rhs dei termini noti (nome del vincolo, es. 'assign_3' o un vincolo aggiuntivo -> valore).
+    """
+    name: str
+    objective: dict = field(default_factory=dict)
+    rhs: dict = field(default_factory=dict)
+
+
+@dataclass
+class ScenarioResult:
+    name: str
+    status: SolveStatus
+    objective: float = None
+    values: dict = None
+    iterations: int = 0
+    nodes: int = 0
+    time_ms: float = 0.0
+    warm_start: bool = False  # se il solve è partito dalla soluzione (o dalla base) dello scenario precedente
+    error: str = None
+
+
+class _ScenarioWorker:
+    """
+    Problema CPLEX costruito una sola volta e riusato per tutti gli scenari assegnati al worker:
+    ogni scenario modifica solo costi e termini noti, che vengono ripristinati subito dopo.
+    Con relaxed=True si risolve il rilassamento LP e CPLEX riparte dalla base fattorizzata
+    dello scenario precedente; per l'ILP la soluzione precedente diventa un MIP start (riparato
+    se non è più ammissibile).
+    """
+    def __init__(self, solver: Solver, relaxed, threads, clock):
+        self.solver = solver
+        self.relaxed = relaxed
+        self.clock = clock
+        self.c = solver.get_costs()
+        self.mkp = cplex.Cplex()
+        self.mkp.set_log_stream(None)
+        self.mkp.set_error_stream(None)
+        self.mkp.set_warning_stream(None)
+        self.mkp.set_results_stream(None)
+        self.mkp.objective.set_sense(self.mkp.objective.sense.minimize)
+        self.var_names = solver._build_ilp(self.mkp, self.c)
+        if relaxed:
+            # Il rilassamento non ammette insiemi SOS; senza presolve la base resta riferita al modello originale
+            self.mkp.SOS.delete()
+            self.mkp.set_problem_type(self.mkp.problem_type.LP)
+            self.mkp.parameters.preprocessing.presolve.set(0)
+        if threads:
+            self.mkp.parameters.threads.set(threads)
+        if solver.time_limit:
+            self.mkp.parameters.timelimit.set(float(solver.time_limit))
+        self.has_start = False
+
+    def close(self):
+        self.mkp.end()
+
+    def solve(self, scenario: Scenario):
+        costs = self.solver.model.fixing_indices(scenario.objective)
+        old_rhs = (dict(zip(scenario.rhs, self.mkp.linear_constraints.get_rhs(list(scenario.rhs))))
+                   if scenario.rhs else {})
+        result = ScenarioResult(scenario.name, SolveStatus.ERROR, warm_start=self.has_start)
+        start = self.clock.now()
+        try:
+            if costs:
+                self.mkp.objective.set_linear(list(costs.items()))
+            if scenario.rhs:
+                self.mkp.linear_constraints.set_rhs([(name, float(value)) for name, value in scenario.rhs.items()])
+            self.mkp.solve()
+
+            result.status = status_from_cplex(self.mkp.solution.get_status())
+            result.iterations = self.mkp.solution.progress.get_num_iterations()
+            self.clock.charge(result.iterations)
+            if not self.relaxed:
+                result.nodes = self.mkp.solution.progress.get_num_nodes_processed()
+            if result.status.has_solution:
+                values = self.mkp.solution.get_values()
+                result.objective = self.mkp.solution.get_objective_value()
+                result.values = dict(zip(self.var_names, values))
+                self._set_start(values)
+        except cplex.CplexError as e:
+            result.status, result.error = SolveStatus.ERROR, str(e)
+        finally:
+            if costs:
+                self.mkp.objective.set_linear([(j, float(self.c[j])) for j in costs])
+            if old_rhs:
+                self.mkp.linear_constraints.set_rhs(list(old_rhs.items()))
+        result.time_ms = self.clock.elapsed_ms(start)
+        return result
+
+    def _set_start(self, values):
+        if self.relaxed:
+            # La base resta nel problema: il prossimo solve riparte da lì
+            self.has_start = True
+            return
+        if self.mkp.MIP_starts.get_num():
+            self.mkp.MIP_starts.delete()
+        self.mkp.MIP_starts.add(cplex.SparsePair(ind=list(range(len(values))), val=list(values)),
+                                self.mkp.MIP_starts.effort_level.repair, "previous_scenario")
+        self.has_start = True
+
+
+def solve_scenarios(solver: Solver, scenarios, workers=1, relaxed=False, clock=WALL_CLOCK):
+    """
+    Risolve il modello di solver (con i suoi fissaggi, regole di branching e limite di tempo) per
+    ogni scenario. Gli scenari sono distribuiti a rotazione su `workers` thread, ciascuno con il
+    proprio problema CPLEX (un thread CPLEX per worker quando se ne usa più di uno): scenari
+    simili assegnati allo stesso worker si avvantaggiano del warm start.
+    Il presolve di CPLEX viene rifatto per ogni scenario, perché le sue riduzioni dipendono da costi
+    e termini noti. Restituisce i ScenarioResult nello stesso ordine di scenarios.
+    """
+    scenarios = list(scenarios)
+    if not scenarios:
+        return []
+    workers = max(1, min(workers, len(scenarios)))
+    threads = 1 if workers > 1 else 0
+
+    def run(chunk):
+        worker = _ScenarioWorker(solver, relaxed, threads, clock)
+        try:
+            return [(index, worker.solve(scenario)) for index, scenario in chunk]
+        finally:
+            worker.close()
+
+    indexed = list(enumerate(scenarios))
+    chunks = [indexed[k::workers] for k in range(workers)]
+    results = [None] * len(scenarios)
+    with ThreadPoolExecutor(max_workers=workers, thread_name_prefix="scenario") as pool:
+        for chunk_results in pool.map(run, chunks):
+            for index, result in chunk_results:
+                results[index] = result
+    return results
+
+
+def scenario_statistics(results):
+    """Riepilogo degli esiti: conteggio per stato, obiettivo minimo/medio/massimo, tempi e iterazioni."""
+    objectives = [r.objective for r in results if r.objective is not None]
+    statuses = {}
+    for r in results:
+        statuses[r.status.value] = statuses.get(r.status.value, 0) + 1
+    return {
+        'scenarios': len(results),
+        'statuses': statuses,
+        'min_objective': min(objectives, default=None),
+        'mean_objective': sum(objectives) / len(objectives) if objectives else None,
+        'max_objective': max(objectives, default=None),
+        'total_time_ms': sum(r.time_ms for r in results),
+        'total_iterations': sum(r.iterations for r in results),
+        'warm_starts': sum(r.warm_start for r in results),
+    }
+
+
+def print_scenario_results(results):
+    print("\n" + "=" * 60)
+    print("RISULTATI PER SCENARIO")
+    print("=" * 60)
+    for r in results:
+        objective = f"{r.objective:.4f}" if r.objective is not None else "-"
+        print(f"{r.name:<20} {r.status.value:<22} {objective:>14} {r.iterations:>8} it "
+              f"{r.time_ms:>9.1f} ms{' (warm)' if r.warm_start else ''}")
+    stats = scenario_statistics(results)
+    print(f"Totale: {stats['scenarios']} scenari, {stats['total_time_ms']:.1f} ms, "
+          f"{stats['warm_starts']} con warm start | stati: {stats['statuses']}")
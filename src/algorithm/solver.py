@@ -1,13 +1,22 @@
+import math
+from contextlib import contextmanager
+
 import numpy as np
 import cplex
 from pathlib import Path
+from algorithm.branching import BranchingStrategy
 from utility.facilityLocation import FacilityLocationModel
+from utility.errors import SolveStatus, SolverError, status_from_cplex, error_for_status
+from utility.solutionCheck import check_solution, print_violation_report
 
-def print_solution(prob: cplex.Cplex()):
-    """Stampa la soluzione di un problema CPLEX in modo leggibile."""
+def print_solution(prob: cplex.Cplex(), scale=1.0):
+    """
+    Stampa la soluzione di un problema CPLEX in modo leggibile.
+    Se l'obiettivo è stato riscalato, scale riporta il valore nella scala originale.
+    """
     try:
         sol_status_str = prob.solution.get_status_string()
-        obj_value = prob.solution.get_objective_value()
+        obj_value = prob.solution.get_objective_value() / scale
 
         print(f"Solution status = {sol_status_str}")
         print(f"Solution value  = {obj_value:.4f}")
@@ -25,15 +34,100 @@ def print_solution(prob: cplex.Cplex()):
             return None, False, "error"
 
 
+def is_integral_objective(c, integer_mask=None, tolerance=1e-9):
+    """
+    Indica se l'obiettivo assume solo valori interi sulle soluzioni ammissibili:
+    tutte le variabili con costo non nullo sono intere e hanno coefficiente intero.
+    integer_mask indica le variabili intere (default: tutte, come in UFL dove sono binarie).
+    """
+    for j, cost in enumerate(c):
+        if abs(cost) <= tolerance:
+            continue
+        if integer_mask is not None and not integer_mask[j]:
+            return False
+        if abs(cost - round(cost)) > tolerance:
+            return False
+    return True
+
+
+def objective_scale_factor(c, min_abs=1e-2, max_abs=1e4):
+    """
+    Fattore di scala (potenza di 2, quindi senza errori di arrotondamento) per un obiettivo
+    mal scalato: riporta il massimo coefficiente in modulo in [1, 2).
+    Restituisce 1.0 se i coefficienti sono già in [min_abs, max_abs].
+    """
+    max_coeff = max((abs(cost) for cost in c), default=0.0)
+    if max_coeff == 0.0 or min_abs <= max_coeff <= max_abs:
+        return 1.0
+    return 2.0 ** -math.floor(math.log2(max_coeff))
+
+
+def rounded_dual_bound(bound, integral_objective, tolerance=1e-6):
+    """Con obiettivo intero il bound duale (minimizzazione) può essere arrotondato per eccesso."""
+    if bound is None or not integral_objective:
+        return bound
+    return float(math.ceil(bound - tolerance))
+
+
+def gub_rows(model: FacilityLocationModel):
+    """
+    Righe GUB (generalized upper bound) del modello: sum x_j = 1 oppure sum x_j <= 1 su variabili
+    binarie con coefficienti unitari. Comprendono i vincoli di assegnamento di ogni cliente e i
+    vincoli aggiuntivi della stessa forma. Restituisce un elenco di (nome, indici).
+    """
+    p, r = model.get_num_facilities(), model.get_num_customers()
+    rows = [(model.constraint_info(v)['name'], [p + u * r + v for u in range(p)]) for v in range(r)]
+    for constraint in model.side_constraints:
+        if (constraint['sense'] in ('E', 'L') and abs(constraint['rhs'] - 1.0) < 1e-12 and
+                len(constraint['indices']) > 1 and all(abs(a - 1.0) < 1e-12 for a in constraint['coeffs'])):
+            rows.append((constraint['name'], list(constraint['indices'])))
+    return rows
+
+
+class _IncumbentCallback:
+    """Callback generica di CPLEX (contesto candidate): inoltra ogni soluzione intera che migliora la precedente."""
+    def __init__(self, on_incumbent, var_names, maximize):
+        self.on_incumbent = on_incumbent
+        self.var_names = var_names
+        self.maximize = maximize
+        self.best = None
+
+    def invoke(self, context):
+        if not (context.in_candidate() and context.is_candidate_point()):
+            return
+        objective = context.get_candidate_objective()
+        if self.best is not None and (objective <= self.best if self.maximize else objective >= self.best):
+            return
+        self.best = objective
+        self.on_incumbent(objective, dict(zip(self.var_names, context.get_candidate_point())))
+
+
 class Solver:
-    def __init__(self, model: FacilityLocationModel):
+    def __init__(self, model: FacilityLocationModel, debug=False, gub_branching=False,
+                 branching: BranchingStrategy = None, time_limit=0):
         self.model = model
+        # Valori delle variabili dell'ultima soluzione ILP ottima (nome -> valore)
+        self.optimal_values = None
+        # Stato dell'ultima risoluzione ILP
+        self.last_status = None
+        # Se impostata, on_incumbent(obiettivo, valori) riceve ogni soluzione migliorante trovata dal branch and bound
+        self.on_incumbent = None
+        # In modalità debug ogni soluzione viene verificata vincolo per vincolo (vedi violation_report)
+        self.debug = debug
+        self.violation_report = None
+        # Fissaggi temporanei (indice -> valore) applicati a ogni ILP costruito, vedi fixing()
+        self.fixed_variables = {}
+        # Con gub_branching le righe GUB diventano insiemi SOS1: CPLEX ramifica su sottoinsiemi della riga
+        self.gub_branching = gub_branching
+        # Regole di branching e di selezione dei nodi (None = default di CPLEX)
+        self.branching = branching
+        # Limite di tempo del branch and bound in secondi (0 = nessuno): allo scadere si usa la miglior soluzione
+        self.time_limit = time_limit
+        # cplex.Aborter opzionale: abort() interrompe il branch and bound, che restituisce la miglior soluzione
+        self.aborter = None
 
-    def get_problem_data(self, maximize=False):
-        """
-        Prepara i dati del problema UFL.
-        Se maximize=True, inverte il segno del vettore dei costi `c`.
-        """
+    def get_costs(self, maximize=False):
+        """Vettore dei costi `c` del problema UFL (con segno invertito se maximize=True)."""
         p = self.model.get_num_facilities()
         r = self.model.get_num_customers()
         fixed_costs = self.model.get_fixed_costs()
@@ -49,6 +143,18 @@ class Solver:
         # --- TRASFORMAZIONE IN MASSIMIZZAZIONE ---
         if maximize:
             c = -c
+        return c
+
+    def get_problem_data(self, maximize=False):
+        """
+        Prepara i dati del problema UFL con la matrice A densa, per le analisi che ne hanno bisogno
+        (statistiche, struttura, scelta automatica della strategia). Per costruire l'LP vedi add_problem_rows.
+        Se maximize=True, inverte il segno del vettore dei costi `c`.
+        """
+        p = self.model.get_num_facilities()
+        r = self.model.get_num_customers()
+        n_vars = p + (r * p)
+        c = self.get_costs(maximize)
 
         # --- VINCOLI ---
         A_list = []
@@ -72,23 +178,202 @@ class Solver:
                 A_list.append(row.tolist())
                 b_list.append(0.0)
 
+        # Vincoli aggiuntivi dell'utente, riportati nella forma a x <= b
+        for constraint in self.model.side_constraints:
+            row = np.zeros(n_vars, dtype=np.float64)
+            row[list(constraint['indices'])] = list(constraint['coeffs'])
+            if constraint['sense'] in ('L', 'E'):
+                A_list.append(row.tolist())
+                b_list.append(constraint['rhs'])
+            if constraint['sense'] in ('G', 'E'):
+                A_list.append((-row).tolist())
+                b_list.append(-constraint['rhs'])
+
         A = np.array(A_list, dtype=np.float64)
         b = np.array(b_list, dtype=np.float64)
         return c, A, b
 
 
 
-    def determine_optimal(self, instance_path: Path, maximize=False):
+    def iter_problem_rows(self):
+        """
+        Genera le righe di get_problem_data in forma sparsa (indici, valori, rhs), nello stesso ordine,
+        senza costruire la matrice densa: per ogni cliente la coppia sum_u y_uv <= 1, -sum_u y_uv <= -1,
+        poi i vincoli di collegamento y_uv - x_u <= 0.
+        """
+        p = self.model.get_num_facilities()
+        r = self.model.get_num_customers()
+        for v in range(r):
+            indices = [p + u * r + v for u in range(p)]
+            yield indices, [1.0] * p, 1.0
+            yield indices, [-1.0] * p, -1.0
+        for u in range(p):
+            for v in range(r):
+                yield [p + u * r + v, u], [1.0, -1.0], 0.0
+        for constraint in self.model.side_constraints:
+            if constraint['sense'] in ('L', 'E'):
+                yield list(constraint['indices']), list(constraint['coeffs']), constraint['rhs']
+            if constraint['sense'] in ('G', 'E'):
+                yield list(constraint['indices']), [-a for a in constraint['coeffs']], -constraint['rhs']
+
+    def add_problem_rows(self, prob: cplex.Cplex, chunk_rows=10_000):
+        """
+        Aggiunge a prob i vincoli di get_problem_data (nomi c{i}, verso 'L') a blocchi di chunk_rows righe
+        sparse, senza costruire la matrice densa. Restituisce il numero di righe aggiunte.
+        """
+        n_rows, batch = 0, []
+
+        def add_batch():
+            prob.linear_constraints.add(
+                lin_expr=[cplex.SparsePair(ind=indices, val=[float(a) for a in values]) for indices, values, _ in batch],
+                rhs=[float(rhs) for _, _, rhs in batch], senses=['L'] * len(batch),
+                names=[f"c{n_rows - len(batch) + k}" for k in range(len(batch))]
+            )
+
+        for row in self.iter_problem_rows():
+            batch.append(row)
+            n_rows += 1
+            if len(batch) >= chunk_rows:
+                add_batch()
+                batch = []
+        if batch:
+            add_batch()
+        return n_rows
+
+    def write_problem_rows(self, builder):
+        """Scrive i vincoli del problema in uno StreamingModelBuilder e restituisce il numero di righe."""
+        n_rows = 0
+        for indices, values, rhs in self.iter_problem_rows():
+            builder.add_row(indices, values, rhs, 'L')
+            n_rows += 1
+        return n_rows
+
+    def _build_ilp(self, mkp: cplex.Cplex, c, relaxed_groups=(), fixed_groups=None):
+        """
+        Aggiunge a mkp variabili e vincoli dell'ILP UFL e restituisce i nomi delle variabili.
+        relaxed_groups e fixed_groups hanno lo stesso significato di determine_optimal.
+        """
+        p = self.model.get_num_facilities()
+        r = self.model.get_num_customers()
+        nCols = p + (r * p)
+
+        var_types = [mkp.variables.type.binary] * nCols
+        for group in relaxed_groups:
+            for i in self.model.get_variable_group(group):
+                var_types[i] = mkp.variables.type.continuous
+        var_names = ["x" + str(i) for i in range(nCols)]
+        mkp.variables.add(obj=c.tolist(), lb=[0.0] * nCols, ub=[1.0] * nCols, names=var_names, types=var_types)
+
+        for group, value in (fixed_groups or {}).items():
+            indices = self.model.get_variable_group(group)
+            mkp.variables.set_lower_bounds([(i, float(value)) for i in indices])
+            mkp.variables.set_upper_bounds([(i, float(value)) for i in indices])
+        if self.fixed_variables:
+            mkp.variables.set_lower_bounds(list(self.fixed_variables.items()))
+            mkp.variables.set_upper_bounds(list(self.fixed_variables.items()))
+
+        constraints_to_add = []
+        rhs_to_add = []
+        senses_to_add = []
+
+        # Vincoli di uguaglianza: sum_u y_uv = 1 per ogni cliente v
+        for v in range(r):
+            row_indices = [p + u * r + v for u in range(p)]
+            row_values = [1.0] * p
+            constraints_to_add.append(cplex.SparsePair(ind=row_indices, val=row_values))
+            rhs_to_add.append(1.0)
+            senses_to_add.append('E') # 'E' per Equality
+
+        # Vincoli di disuguaglianza: y_uv <= x_u  ->  y_uv - x_u <= 0 strong form
+        for u in range(p):
+            for v in range(r):
+                row_indices = [p + u * r + v, u]
+                row_values = [1.0, -1.0]
+                constraints_to_add.append(cplex.SparsePair(ind=row_indices, val=row_values))
+                rhs_to_add.append(0.0)
+                senses_to_add.append('L') # 'L' per Less than or equal
+
+        mkp.linear_constraints.add(
+            lin_expr=constraints_to_add,
+            rhs=rhs_to_add,
+            senses=senses_to_add,
+            names=[self.model.constraint_info(i)['name'] for i in range(len(constraints_to_add))]
+        )
+
+        side_constraints = self.model.side_constraints
+        if side_constraints:
+            mkp.linear_constraints.add(
+                lin_expr=[cplex.SparsePair(ind=list(s['indices']), val=list(s['coeffs'])) for s in side_constraints],
+                rhs=[float(s['rhs']) for s in side_constraints],
+                senses=[s['sense'] for s in side_constraints],
+                names=[s['name'] for s in side_constraints]
+            )
+
+        if self.gub_branching:
+            self._add_gub_sets(mkp, c)
+        if self.branching is not None:
+            self.branching.apply(mkp)
+        return var_names
+
+    def _add_gub_sets(self, mkp: cplex.Cplex, c):
+        """
+        Dichiara ogni riga GUB come insieme SOS1. I pesi seguono il costo crescente delle variabili,
+        così ogni ramo del branching su SOS separa le alternative più economiche dalle più costose
+        invece di fissare una singola variabile.
+        """
+        rows = gub_rows(self.model)
+        for name, indices in rows:
+            ordered = sorted(indices, key=lambda j: (c[j], j))
+            mkp.SOS.add(type=mkp.SOS.type.SOS1,
+                        SOS=cplex.SparsePair(ind=ordered, val=[float(k + 1) for k in range(len(ordered))]),
+                        name=f"gub_{name}")
+        print(f"GUB branching: {len(rows)} righe dichiarate come insiemi SOS1.")
+
+
+    @contextmanager
+    def fixing(self, fixings):
+        """
+        Fissa temporaneamente alcune variabili (indice o nome -> valore) per le risoluzioni
+        eseguite nel blocco with, es. per euristiche tipo RINS o analisi "what-if":
+
+            with solver.fixing({'open[0]': 1, 'open[3]': 0}):
+                value = solver.determine_optimal(path)
+
+        All'uscita i fissaggi precedenti vengono ripristinati.
+        """
+        previous = self.fixed_variables
+        self.fixed_variables = {**previous, **self.model.fixing_indices(fixings)}
+        try:
+            yield self
+        finally:
+            self.fixed_variables = previous
+
+    def _check_solution(self, relaxed_groups=(), tolerance=1e-6):
+        """In modalità debug verifica optimal_values e stampa le violazioni oltre la tolleranza."""
+        if not self.debug or self.optimal_values is None:
+            return
+        self.violation_report = check_solution(self.model, self.optimal_values, tolerance, relaxed_groups)
+        if not self.violation_report['feasible']:
+            print_violation_report(self.violation_report)
+
+    def determine_optimal(self, instance_path: Path, maximize=False, relaxed_groups=(), fixed_groups=None,
+                          cutoff=None, objective_bound=None):
         """
         Risolve l'ILP per trovare la soluzione ottima di riferimento.
         Questa versione costruisce i vincoli direttamente per maggiore chiarezza ed efficienza.
+        relaxed_groups: gruppi (o tag) di variabili da rilassare a continue in [0, 1].
+        fixed_groups: dizionario gruppo -> valore a cui fissare le variabili del gruppo.
+        cutoff: il branch and bound scarta i nodi il cui bound è peggiore di questo valore.
+        objective_bound: se indicato, l'obiettivo diventa il vincolo c·x <= K (>= se maximize)
+            e si cerca una qualsiasi soluzione ammissibile; viene restituito il suo costo.
+        Solleva un SolverError tipizzato (InfeasibleError, LimitReachedError, ...) se non c'è soluzione.
         """
         p = self.model.get_num_facilities()
         r = self.model.get_num_customers()
         nCols = p + (r * p)
         name = instance_path.stem
 
-        c, _, _ = self.get_problem_data(maximize=maximize)
+        c = self.get_costs(maximize=maximize)
 
         try:
             with cplex.Cplex() as mkp:
@@ -101,49 +386,242 @@ class Solver:
                 mkp.set_warning_stream(None)
                 mkp.set_results_stream(None)
 
-                var_types = [mkp.variables.type.binary] * nCols
-                var_names = ["x" + str(i) for i in range(nCols)]
-                mkp.variables.add(obj=c.tolist(), names=var_names, types=var_types)
-
+                var_names = self._build_ilp(mkp, c, relaxed_groups, fixed_groups)
+                if self.aborter is not None:
+                    mkp.use_aborter(self.aborter)
+                if self.time_limit:
+                    mkp.parameters.timelimit.set(float(self.time_limit))
 
+                if cutoff is not None:
+                    if maximize:
+                        mkp.parameters.mip.tolerances.lowercutoff.set(float(cutoff))
+                    else:
+                        mkp.parameters.mip.tolerances.uppercutoff.set(float(cutoff))
 
-                constraints_to_add = []
-                rhs_to_add = []
-                senses_to_add = []
+                feasibility_only = objective_bound is not None
+                if feasibility_only:
+                    # Vincolo obiettivo: basta la prima soluzione che lo rispetta
+                    mkp.objective.set_linear([(i, 0.0) for i in range(nCols)])
+                    mkp.linear_constraints.add(
+                        lin_expr=[cplex.SparsePair(ind=list(range(nCols)), val=c.tolist())],
+                        rhs=[float(objective_bound)], senses=['G' if maximize else 'L'],
+                        names=["objective_bound"]
+                    )
+                    mkp.parameters.mip.limits.solutions.set(1)
 
-                # Vincoli di uguaglianza: sum_u y_uv = 1 per ogni cliente v
-                for v in range(r):
-                    row_indices = [p + u * r + v for u in range(p)]
-                    row_values = [1.0] * p
-                    constraints_to_add.append(cplex.SparsePair(ind=row_indices, val=row_values))
-                    rhs_to_add.append(1.0)
-                    senses_to_add.append('E') # 'E' per Equality
-
-                # Vincoli di disuguaglianza: y_uv <= x_u  ->  y_uv - x_u <= 0 strong form
-                for u in range(p):
-                    for v in range(r):
-                        row_indices = [p + u * r + v, u]
-                        row_values = [1.0, -1.0]
-                        constraints_to_add.append(cplex.SparsePair(ind=row_indices, val=row_values))
-                        rhs_to_add.append(0.0)
-                        senses_to_add.append('L') # 'L' per Less than or equal
-
-                mkp.linear_constraints.add(
-                    lin_expr=constraints_to_add,
-                    rhs=rhs_to_add,
-                    senses=senses_to_add
-                )
+                if self.on_incumbent is not None and not feasibility_only:
+                    mkp.set_callback(_IncumbentCallback(self.on_incumbent, var_names, maximize),
+                                     cplex.callbacks.Context.id.candidate)
 
                 print(f"Risolvendo ILP per {name} per trovare l'ottimo di riferimento...")
                 mkp.solve()
 
-                if mkp.solution.get_status() in [101, 102]: # 101=optimal, 102=optimal integer
+                self.last_status = status_from_cplex(mkp.solution.get_status())
+                aborted = self.aborter is not None and self.aborter.is_aborted()
+
+                if feasibility_only and self.last_status.has_solution: # anche 104=limite soluzioni raggiunto
+                    values = mkp.solution.get_values()
+                    self.optimal_values = dict(zip(var_names, values))
+                    self._check_solution(relaxed_groups)
+                    feasible_sol = float(np.dot(c, values))
+                    print(f"Soluzione ammissibile con obiettivo entro {objective_bound} trovata. Valore: {feasible_sol:.4f}")
+                    return feasible_sol
+                elif self.last_status == SolveStatus.OPTIMAL: # 101=optimal, 102=optimal integer
                     optimal_sol = mkp.solution.get_objective_value()
+                    self.optimal_values = dict(zip(var_names, mkp.solution.get_values()))
+                    self._check_solution(relaxed_groups)
                     print(f"Soluzione ottima di riferimento trovata. Valore: {optimal_sol:.4f}")
                     return optimal_sol
+                elif (self.time_limit or aborted) and self.last_status == SolveStatus.LIMIT_WITH_SOLUTION:
+                    best_sol = mkp.solution.get_objective_value()
+                    self.optimal_values = dict(zip(var_names, mkp.solution.get_values()))
+                    self._check_solution(relaxed_groups)
+                    reason = "Solve annullato" if aborted else "Budget di tempo dell'albero esaurito"
+                    print(f"{reason}: miglior soluzione {best_sol:.4f} "
+                          f"(gap MIP {mkp.solution.MIP.get_mip_relative_gap():.2%}).")
+                    return best_sol
                 else:
                     print(f"ATTENZIONE: Soluzione ottima non trovata. Status: {mkp.solution.get_status_string()}")
-                    return None
+                    raise error_for_status(self.last_status, f"{name}: {mkp.solution.get_status_string()}")
         except cplex.CplexError as e:
             print(f"Errore CPLEX in determine_optimal: {e}")
-            return None
\ No newline at end of file
+            self.last_status = SolveStatus.ERROR
+            raise SolverError(f"Errore CPLEX in determine_optimal: {e}") from e
+
+
+    def _soft_rows(self, soft=None, weights=None, default_weight=1.0):
+        """
+        Vincoli da rendere soft, come elenco di (nome, verso, peso).
+        soft: None = tutti; altrimenti gruppi ('assignment', 'linking', 'side'), tag o nomi di vincoli.
+        weights: dizionario nome/tag/gruppo -> peso (vale il più specifico), default_weight per gli altri.
+        """
+        weights = weights or {}
+        selected = None if soft is None else set(soft)
+        rows = []
+        for i in range(self.model.get_num_constraints()):
+            info = self.model.constraint_info(i)
+            keys = [info['name']] + sorted(info['tags']) + [info['group']]
+            if selected is None or selected.intersection(keys):
+                sense = 'E' if info['group'] == 'assignment' else 'L'
+                weight = next((weights[k] for k in keys if k in weights), default_weight)
+                rows.append((info['name'], sense, weight))
+        for constraint in self.model.side_constraints:
+            keys = [constraint['name'], 'side']
+            if selected is None or selected.intersection(keys):
+                weight = next((weights[k] for k in keys if k in weights), default_weight)
+                rows.append((constraint['name'], constraint['sense'], weight))
+        return rows
+
+    def soft_solve(self, instance_path: Path, soft=None, weights=None, default_weight=1.0, cost_weight=0.0,
+                   tolerance=1e-6):
+        """
+        Modalità elastica per modelli sovravincolati: i vincoli selezionati (vedi _soft_rows) ricevono
+        variabili di scarto s >= 0 (a·x - s <= b, a·x + s >= b, entrambe per le uguaglianze) e si minimizza
+        la violazione pesata sum w·s, più cost_weight volte il costo UFL. Gli altri vincoli restano rigidi,
+        quindi il problema è inammissibile solo se lo sono già loro.
+        Restituisce {'total_violation', 'cost', 'violations'} con i vincoli violati oltre la tolleranza,
+        dal maggiore contributo pesato; optimal_values contiene la soluzione (senza le variabili di scarto).
+        """
+        name = instance_path.stem
+        c = self.get_costs()
+        rows = self._soft_rows(soft, weights, default_weight)
+        negative = [row_name for row_name, _, weight in rows if weight < 0]
+        if negative:
+            raise ValueError(f"Pesi negativi per i vincoli soft: {', '.join(negative)}")
+
+        try:
+            with cplex.Cplex() as mkp:
+                mkp.set_problem_name(f"{name}_soft_ILP")
+                mkp.objective.set_sense(mkp.objective.sense.minimize)
+
+                mkp.set_log_stream(None)
+                mkp.set_error_stream(None)
+                mkp.set_warning_stream(None)
+                mkp.set_results_stream(None)
+
+                var_names = self._build_ilp(mkp, c * cost_weight)
+                if self.time_limit:
+                    mkp.parameters.timelimit.set(float(self.time_limit))
+
+                # Scarto di eccesso (coefficiente -1) per L ed E, di difetto (+1) per G ed E
+                slacks = []
+                for row_name, sense, weight in rows:
+                    row = mkp.linear_constraints.get_indices(row_name)
+                    if sense in ('L', 'E'):
+                        slacks.append((row_name, 'excess', row, -1.0, weight))
+                    if sense in ('G', 'E'):
+                        slacks.append((row_name, 'deficit', row, 1.0, weight))
+                if slacks:
+                    mkp.variables.add(
+                        obj=[float(weight) for *_, weight in slacks],
+                        lb=[0.0] * len(slacks),
+                        names=[f"soft_{row_name}_{kind}" for row_name, kind, *_ in slacks],
+                        columns=[cplex.SparsePair(ind=[row], val=[coeff]) for _, _, row, coeff, _ in slacks]
+                    )
+
+                print(f"Risolvendo ILP elastico per {name} ({len(rows)} vincoli soft)...")
+                mkp.solve()
+
+                self.last_status = status_from_cplex(mkp.solution.get_status())
+                if not self.last_status.has_solution:
+                    print(f"ATTENZIONE: Nessuna soluzione elastica. Status: {mkp.solution.get_status_string()}")
+                    raise error_for_status(self.last_status, f"{name}: {mkp.solution.get_status_string()}")
+
+                values = mkp.solution.get_values()
+                self.optimal_values = dict(zip(var_names, values[:len(var_names)]))
+                slack_values = values[len(var_names):]
+        except cplex.CplexError as e:
+            print(f"Errore CPLEX in soft_solve: {e}")
+            self.last_status = SolveStatus.ERROR
+            raise SolverError(f"Errore CPLEX in soft_solve: {e}") from e
+
+        violations = [{'name': row_name, 'kind': kind, 'weight': weight, 'violation': value,
+                       'weighted': weight * value}
+                      for (row_name, kind, _, _, weight), value in zip(slacks, slack_values) if value > tolerance]
+        violations.sort(key=lambda v: -v['weighted'])
+        result = {
+            'total_violation': sum(v['weighted'] for v in violations),
+            'cost': float(np.dot(c, values[:len(var_names)])),
+            'violations': violations,
+        }
+        print(f"Violazione pesata totale: {result['total_violation']:.4f} su {len(violations)} vincoli "
+              f"(costo UFL {result['cost']:.4f}).")
+        return result
+
+    def solve_scenarios(self, scenarios, workers=1, relaxed=False):
+        """Risolve il modello per ogni Scenario riusando problema e warm start, vedi algorithm/scenarios.py."""
+        from algorithm.scenarios import solve_scenarios
+        return solve_scenarios(self, scenarios, workers, relaxed)
+
+    def enumerate_solutions(self, instance_path: Path, maximize=False, optimal_only=True,
+                            tolerance=1e-6, max_solutions=100):
+        """
+        Enumera le soluzioni dell'ILP tramite il solution pool di CPLEX.
+        optimal_only=True: tutte le soluzioni entro `tolerance` (assoluta) dall'ottimo;
+        altrimenti tutte le soluzioni ammissibili trovate, fino a max_solutions.
+        Restituisce una lista di dizionari {'objective', 'values'} ordinata per obiettivo.
+        """
+        name = instance_path.stem
+        c = self.get_costs(maximize=maximize)
+
+        try:
+            with cplex.Cplex() as mkp:
+                mkp.set_problem_name(f"{name}_solution_pool")
+                mkp.objective.set_sense(mkp.objective.sense.maximize if maximize else mkp.objective.sense.minimize)
+
+                mkp.set_log_stream(None)
+                mkp.set_error_stream(None)
+                mkp.set_warning_stream(None)
+                mkp.set_results_stream(None)
+
+                var_names = self._build_ilp(mkp, c)
+
+                # Intensità massima: enumerazione (quasi) completa delle soluzioni
+                mkp.parameters.mip.pool.intensity.set(4)
+                mkp.parameters.mip.pool.capacity.set(max_solutions)
+                mkp.parameters.mip.limits.populate.set(max_solutions)
+                if optimal_only:
+                    mkp.parameters.mip.pool.absgap.set(tolerance)
+
+                print(f"Enumerazione delle soluzioni per {name} (max {max_solutions})...")
+                mkp.populate_solution_pool()
+
+                solutions = []
+                for k in range(mkp.solution.pool.get_num()):
+                    solutions.append({
+                        'objective': mkp.solution.pool.get_objective_value(k),
+                        'values': dict(zip(var_names, mkp.solution.pool.get_values(k)))
+                    })
+                solutions.sort(key=lambda s: s['objective'], reverse=maximize)
+
+                if optimal_only and solutions:
+                    best = solutions[0]['objective']
+                    solutions = [s for s in solutions if abs(s['objective'] - best) <= tolerance]
+
+                print(f"Trovate {len(solutions)} soluzioni.")
+                return solutions
+        except cplex.CplexError as e:
+            print(f"Errore CPLEX in enumerate_solutions: {e}")
+            return []
+
+
+    def report_group_totals(self, values: dict = None):
+        """
+        Riassume la soluzione per gruppo di variabili (predefinito o tag):
+        numero di variabili, somma dei valori e contributo al costo.
+        """
+        values = values if values is not None else self.optimal_values
+        if values is None:
+            return {}
+
+        c = self.get_costs()
+        totals = {}
+        for i in range(self.model.get_num_variables()):
+            info = self.model.variable_info(i)
+            value = values.get(info['name'], 0.0)
+            for group in [info['group']] + sorted(info['tags']):
+                entry = totals.setdefault(group, {'count': 0, 'sum_values': 0.0, 'cost': 0.0})
+                entry['count'] += 1
+                entry['sum_values'] += value
+                entry['cost'] += c[i] * value
+        return totals
@@ -0,0 +1,63 @@
+import numpy as np
+
+from analysis.modelStats import matrix_stats
+
+# scelta automatica della strategia di risoluzione a partire dalla classificazione del modello
+
+LP_METHODS = ('primal', 'dual', 'barrier', 'network')
+
+# Oltre queste dimensioni il simplesso tende a soffrire la degenerazione: si preferisce il barrier
+BARRIER_MIN_NNZ = 1_000_000
+BARRIER_MIN_ROWS = 50_000
+
+
+def is_network_matrix(A: np.ndarray):
+    """Matrice di rete: ogni colonna ha al più due coefficienti non nulli, +1 e -1."""
+    if A.size == 0:
+        return False
+    for column in A.T:
+        nz = column[column != 0]
+        if len(nz) > 2 or np.any(np.abs(nz) != 1.0):
+            return False
+        if len(nz) == 2 and nz[0] == nz[1]:
+            return False
+    return True
+
+
+def select_strategy(c: np.ndarray, A: np.ndarray, b: np.ndarray, stats: dict = None):
+    """
+    Sceglie metodo LP e famiglia di tagli in base alla struttura del modello:
+    - matrice di rete -> simplesso di rete;
+    - LP molto grandi -> barrier (con crossover, necessario per il tableau dei tagli);
+    - matrice 0/+-1 con vincoli di set partitioning/packing -> tagli zero-half ('ZH');
+    - vincoli di knapsack con coefficienti generali -> MIR;
+    - altrimenti primale e GMI (i default storici).
+    Restituisce {'lp_method', 'cut_mode', 'reasons'}.
+    """
+    stats = stats if stats is not None else matrix_stats(c, A, b)
+    classes = stats['constraint_classes']
+    reasons = []
+
+    if is_network_matrix(A):
+        lp_method = 'network'
+        reasons.append("matrice di rete: simplesso di rete")
+    elif stats['nnz'] >= BARRIER_MIN_NNZ or stats['n_rows'] >= BARRIER_MIN_ROWS:
+        lp_method = 'barrier'
+        reasons.append(f"LP grande ({stats['n_rows']} righe, {stats['nnz']} non zeri): barrier")
+    else:
+        lp_method = 'primal'
+        reasons.append("LP di dimensione moderata: simplesso primale")
+
+    unit_coefficients = stats['coefficient_range'] == (1.0, 1.0)
+    set_rows = sum(classes.get(k, 0) for k in ('set_partitioning', 'set_packing', 'set_covering'))
+    if unit_coefficients and set_rows:
+        cut_mode = 'ZH'
+        reasons.append(f"matrice 0/+-1 con {set_rows} vincoli di set partitioning/packing: tagli zero-half")
+    elif classes.get('knapsack', 0) and not unit_coefficients:
+        cut_mode = 'MIR'
+        reasons.append(f"{classes['knapsack']} vincoli di knapsack: tagli MIR")
+    else:
+        cut_mode = 'GMI'
+        reasons.append("nessuna struttura specifica: tagli GMI")
+
+    return {'lp_method': lp_method, 'cut_mode': cut_mode, 'reasons': reasons}
@@ -0,0 +1,87 @@
+import random
+from dataclasses import replace
+
+from algorithm.branching import BRANCHING_RULES, NODE_SELECTIONS
+from algorithm.gomory import Gomory
+from algorithm.options import SolverOptions, CUT_MODES, HEURISTIC_NAMES, write_options
+from utility.facilityLocation import FacilityLocationModel
+from utility.clock import WALL_CLOCK
+
+# tuning automatico dei parametri: ricerca casuale su un insieme di istanze
+
+# Spazio di ricerca: valori candidati per ciascun parametro
+SEARCH_SPACE = {
+    'cut_mode': list(CUT_MODES),
+    'cut_min_efficacy': [1e-6, 1e-5, 1e-4, 1e-3, 1e-2],
+    'cut_max_age': [1, 2, 3, 5, 10],
+    'reduced_cost_fixing': [True, False],
+    'branching_rule': list(BRANCHING_RULES),
+    'node_selection': list(NODE_SELECTIONS),
+}
+
+
+def sample_options(rng: random.Random, base: SolverOptions):
+    """Estrae una configurazione casuale dallo spazio di ricerca (le euristiche come sottoinsieme disabilitato)."""
+    changes = {name: rng.choice(values) for name, values in SEARCH_SPACE.items()}
+    changes['heuristics_disabled'] = [name for name in HEURISTIC_NAMES if rng.random() < 0.3]
+    changes['heuristics_forced'] = []
+    return replace(base, **changes)
+
+
+def evaluate_options(options: SolverOptions, instance_files, clock_factory=None):
+    """
+    Risolve ogni istanza con i parametri dati e restituisce il punteggio medio:
+    (gap relativo finale, tempo in ms). Sono confrontati in ordine lessicografico: prima il gap.
+    Un'istanza non risolta conta con gap 1.
+    clock_factory (es. utility.clock.WorkClock) crea un orologio nuovo per ogni istanza:
+    con un orologio a unità di lavoro i tempi sono riproducibili tra esecuzioni e macchine.
+    """
+    gaps, times = [], []
+    for file_path in instance_files:
+        clock = clock_factory() if clock_factory is not None else WALL_CLOCK
+        start = clock.now()
+        try:
+            model = FacilityLocationModel.from_file(file_path, exact=options.exact_presolve)
+            stats = Gomory(model, options=options, clock=clock).solve_problem(str(file_path))
+        except Exception as e:
+            print(f"AVVISO: valutazione fallita su {file_path}: {e}")
+            stats = []
+        gaps.append(stats[-1]['relative_gap'] if stats else 1.0)
+        times.append(clock.elapsed_ms(start))
+    n = max(1, len(instance_files))
+    return sum(gaps) / n, sum(times) / n
+
+
+def tune(instance_files, n_trials=10, seed=0, base: SolverOptions = None, output_file=None, clock_factory=None):
+    """
+    Ricerca casuale dei parametri: valuta i default e n_trials configurazioni estratte
+    da SEARCH_SPACE, e restituisce (migliori opzioni, punteggio, storico delle prove).
+    Se output_file è indicato, la migliore configurazione viene salvata come file .ini.
+    clock_factory: orologio usato per misurare i tempi (vedi evaluate_options).
+    """
+    instance_files = list(instance_files)
+    if not instance_files:
+        raise ValueError("Nessuna istanza su cui eseguire il tuning.")
+    base = base if base is not None else SolverOptions()
+    rng = random.Random(seed)
+
+    history = []
+    best_options, best_score = None, None
+    for trial in range(n_trials + 1):
+        # La prova 0 valuta i parametri di partenza come riferimento
+        options = base if trial == 0 else sample_options(rng, base)
+        score = evaluate_options(options, instance_files, clock_factory)
+        history.append({'trial': trial, 'options': options, 'mean_gap': score[0], 'mean_time_ms': score[1]})
+        print(f"[tuning] prova {trial}/{n_trials}: gap medio {score[0]:.6f}, tempo medio {score[1]:.0f} ms "
+              f"(cut_mode={options.cut_mode}, branching_rule={options.branching_rule}, "
+              f"node_selection={options.node_selection})")
+        if best_score is None or score < best_score:
+            best_options, best_score = options, score
+
+    if output_file is not None:
+        write_options(best_options, output_file,
+                      comment=f"Configurazione trovata dal tuning su {len(instance_files)} istanze "
+                              f"({n_trials} prove, seed {seed}).\n"
+                              f"Gap medio {best_score[0]:.6f}, tempo medio {best_score[1]:.0f} ms.")
+        print(f"[tuning] Migliore configurazione salvata in: {output_file}")
+    return best_options, best_score, history
@@ -0,0 +1,143 @@
+import datetime
+import hashlib
+import json
+import sqlite3
+from dataclasses import asdict
+from pathlib import Path
+
+from utility.facilityLocation import FacilityLocationModel
+
+# archivio storico dei solve (SQLite): impronta del modello, parametri, esito e tempi di ogni esecuzione
+
+_SCHEMA = """
+CREATE TABLE IF NOT EXISTS solves (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    timestamp TEXT NOT NULL,
+    instance TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    cut_mode TEXT,
+    options TEXT,
+    status TEXT,
+    objective REAL,
+    bound REAL,
+    final_gap REAL,
+    iterations INTEGER,
+    total_cuts INTEGER,
+    total_time_ms REAL,
+    timings TEXT
+);
+CREATE INDEX IF NOT EXISTS solves_instance ON solves (instance);
+CREATE INDEX IF NOT EXISTS solves_fingerprint ON solves (fingerprint);
+"""
+
+
+def model_fingerprint(model: FacilityLocationModel):
+    """Impronta SHA-256 dei dati del modello (dimensioni, costi, vincoli aggiuntivi): cambia se cambiano i dati."""
+    digest = hashlib.sha256()
+    digest.update(f"{model.get_num_facilities()} {model.get_num_customers()}\n".encode())
+    digest.update(" ".join(repr(float(cost)) for cost in model.get_fixed_costs()).encode())
+    for row in model.get_assignment_costs():
+        digest.update(("\n" + " ".join(repr(float(cost)) for cost in row)).encode())
+    for constraint in model.side_constraints:
+        digest.update(f"\n{list(constraint['indices'])} {list(constraint['coeffs'])} {constraint['sense']} {constraint['rhs']}".encode())
+    return digest.hexdigest()
+
+
+class SolveHistory:
+    """
+    Archivio dei solve su file SQLite (creato al primo uso). Uso:
+
+        with SolveHistory(path) as history:
+            history.record(model, summary, options)
+            rows = history.query(instance="inst_1", limit=10)
+    """
+    def __init__(self, db_path):
+        self.db_path = Path(db_path)
+        self.db_path.parent.mkdir(parents=True, exist_ok=True)
+        self.connection = sqlite3.connect(self.db_path)
+        self.connection.row_factory = sqlite3.Row
+        self.connection.executescript(_SCHEMA)
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    def close(self):
+        self.connection.close()
+
+    def record(self, model: FacilityLocationModel, summary: dict, options=None, timings=None):
+        """
+        Registra un solve a partire dal riepilogo di create_solution_summary.
+        options: SolverOptions (o dizionario) usati; timings: tempi per fase del profiler.
+        Restituisce l'id della riga inserita.
+        """
+        if options is not None and not isinstance(options, dict):
+            options = asdict(options)
+        with self.connection:
+            cursor = self.connection.execute(
+                "INSERT INTO solves (timestamp, instance, fingerprint, cut_mode, options, status, objective, bound,"
+                " final_gap, iterations, total_cuts, total_time_ms, timings)"
+                " VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+                (datetime.datetime.now().isoformat(timespec='seconds'), summary['instance_name'],
+                 model_fingerprint(model), summary.get('cut_mode'),
+                 json.dumps(options, sort_keys=True) if options is not None else None,
+                 summary.get('final_status'), summary.get('optimal_solution'), summary.get('final_lp_solution'),
+                 summary.get('final_gap'), summary.get('total_iterations'), summary.get('total_cuts'),
+                 summary.get('total_time_ms'), json.dumps(timings) if timings else None)
+            )
+        return cursor.lastrowid
+
+    def query(self, instance=None, fingerprint=None, cut_mode=None, status=None, since=None, limit=None):
+        """
+        Restituisce i solve (dizionari, dal più recente) che soddisfano tutti i filtri indicati.
+        since: data/ora ISO (stringa o datetime) da cui partire; options e timings sono già decodificati.
+        """
+        filters, params = [], []
+        for column, value in (('instance', instance), ('fingerprint', fingerprint),
+                              ('cut_mode', cut_mode), ('status', status)):
+            if value is not None:
+                filters.append(f"{column} = ?")
+                params.append(value)
+        if since is not None:
+            filters.append("timestamp >= ?")
+            params.append(since.isoformat(timespec='seconds') if isinstance(since, datetime.datetime) else since)
+
+        sql = "SELECT * FROM solves"
+        if filters:
+            sql += " WHERE " + " AND ".join(filters)
+        sql += " ORDER BY id DESC"
+        if limit is not None:
+            sql += " LIMIT ?"
+            params.append(int(limit))
+
+        rows = []
+        for row in self.connection.execute(sql, params):
+            row = dict(row)
+            for key in ('options', 'timings'):
+                row[key] = json.loads(row[key]) if row[key] else None
+            rows.append(row)
+        return rows
+
+    def fingerprint_changes(self, instance):
+        """Solve di un'istanza in cui l'impronta del modello è cambiata rispetto al solve precedente."""
+        rows = list(reversed(self.query(instance=instance)))
+        return [row for previous, row in zip(rows, rows[1:]) if row['fingerprint'] != previous['fingerprint']]
+
+
+def _number(value, fmt):
+    return format(value, fmt) if value is not None else '-'.rjust(int(fmt.split('.')[0]))
+
+
+def print_history(rows):
+    """Stampa una tabella compatta dei solve restituiti da SolveHistory.query."""
+    if not rows:
+        print("Nessun solve registrato.")
+        return
+    print(f"{'ID':>5} {'Data':<19} {'Istanza':<32} {'Modalità':<8} {'Impronta':<10} {'Stato':<12} "
+          f"{'Ottimo':>12} {'Bound LP':>12} {'Gap':>10} {'Tempo (ms)':>11}")
+    for row in rows:
+        print(f"{row['id']:>5} {row['timestamp']:<19} {row['instance'][:32]:<32} {row['cut_mode'] or '-':<8} "
+              f"{row['fingerprint'][:10]:<10} {row['status'] or '-':<12} {_number(row['objective'], '12.4f')} "
+              f"{_number(row['bound'], '12.4f')} {_number(row['final_gap'], '10.2e')} {_number(row['total_time_ms'], '11.1f')}")
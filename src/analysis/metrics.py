@@ -0,0 +1,105 @@
+import math
+
+# Metriche standard di benchmarking (Berthold, 2013; Dolan & Moré, 2002)
+
+
+def gap_function(primal, dual):
+    """
+    Funzione di gap in [0, 1] usata per gli integrali:
+    1 se uno dei due bound manca o hanno segno opposto, 0 se coincidono,
+    altrimenti |primal - dual| / max(|primal|, |dual|).
+    """
+    if primal is None or dual is None:
+        return 1.0
+    if abs(primal - dual) < 1e-9:
+        return 0.0
+    if primal * dual < 0:
+        return 1.0
+    return min(1.0, abs(primal - dual) / max(abs(primal), abs(dual)))
+
+
+def _integrate_step_function(points, end_time):
+    """
+    Integra una funzione a gradino definita da coppie (tempo, valore):
+    ogni valore resta costante fino al punto successivo (o fino a end_time).
+    """
+    points = sorted(points, key=lambda p: p[0])
+    if not points:
+        return 0.0
+    if end_time is None:
+        end_time = points[-1][0]
+
+    # Prima del primo evento il gap è massimo (nessuna informazione)
+    integral = points[0][0] * 1.0
+    for (t, value), (t_next, _) in zip(points, points[1:] + [(end_time, None)]):
+        integral += value * max(0.0, min(t_next, end_time) - t)
+    return integral
+
+
+def primal_integral(trajectory, optimal_value, end_time=None):
+    """
+    Integrale primale: integrale nel tempo del gap tra l'incumbent e l'ottimo.
+    trajectory è una lista di dict con chiavi 'time', 'primal' (incumbent, può essere None).
+    """
+    points = [(p['time'], gap_function(p.get('primal'), optimal_value)) for p in trajectory]
+    return _integrate_step_function(points, end_time)
+
+
+def primal_dual_integral(trajectory, end_time=None):
+    """
+    Integrale primale-duale: integrale nel tempo del gap tra incumbent e bound duale.
+    trajectory è una lista di dict con chiavi 'time', 'primal', 'dual'.
+    """
+    points = [(p['time'], gap_function(p.get('primal'), p.get('dual'))) for p in trajectory]
+    return _integrate_step_function(points, end_time)
+
+
+def trajectory_from_stats(instance_stats: list[dict]):
+    """
+    Ricostruisce la traiettoria (tempo, primale, duale) dalle statistiche per iterazione
+    di Gomory.solve_problem: il duale è il valore LP corrente (arrotondato se l'obiettivo
+    è intero), il primale l'incumbent delle euristiche ('heuristic_ub') se presente,
+    altrimenti l'ottimo ILP noto.
+    """
+    trajectory = []
+    for s in instance_stats:
+        trajectory.append({
+            'time': s.get('elapsed_time', 0),
+            'primal': s.get('heuristic_ub', s.get('optimal_ilp')),
+            'dual': s.get('rounded_lp_bound', s.get('lp_solution')),
+        })
+    return trajectory
+
+
+def performance_profile(df, metric='total_time_ms', solver_col='cut_mode',
+                        instance_col='instance_name', solved_col=None, taus=None):
+    """
+    Calcola i dati del performance profile di Dolan-Moré.
+    Per ogni istanza p e solver s: r_ps = t_ps / min_s t_ps;
+    rho_s(tau) = frazione di istanze con r_ps <= tau.
+    Se solved_col è indicato, le righe con valore False vengono considerate non risolte (r = inf).
+    Restituisce un DataFrame con colonne [solver_col, 'tau', 'rho'].
+    """
+    import pandas as pd
+
+    data = df[[instance_col, solver_col, metric]].copy()
+    if solved_col is not None:
+        data.loc[~df[solved_col].astype(bool), metric] = math.inf
+
+    # Evita rapporti degeneri quando la metrica migliore è zero
+    data[metric] = data[metric].clip(lower=1e-9)
+    best = data.groupby(instance_col)[metric].transform('min')
+    data['ratio'] = data[metric] / best
+
+    n_instances = data[instance_col].nunique()
+    if taus is None:
+        finite = data.loc[data['ratio'] != math.inf, 'ratio']
+        max_ratio = finite.max() if not finite.empty else 1.0
+        taus = sorted(set([1.0] + finite.tolist() + [max_ratio * 1.05]))
+
+    rows = []
+    for solver, group in data.groupby(solver_col):
+        for tau in taus:
+            rho = (group['ratio'] <= tau).sum() / n_instances
+            rows.append({solver_col: solver, 'tau': tau, 'rho': rho})
+    return pd.DataFrame(rows)
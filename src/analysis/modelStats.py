@@ -0,0 +1,114 @@
+import numpy as np
+
+from utility.facilityLocation import FacilityLocationModel
+
+# statistiche della matrice dei vincoli e classificazione delle righe
+
+
+def _row_class(row: np.ndarray, rhs: float, integer_mask: np.ndarray):
+    """
+    Classifica una riga a x <= rhs:
+    - 'set_partitioning'/'set_packing'/'set_covering': coefficienti +-1 uniformi su binarie, |rhs| = 1;
+    - 'variable_bound': due variabili, di cui almeno una binaria (es. y_uv - x_u <= 0);
+    - 'knapsack': coefficienti positivi su binarie;
+    - 'general' altrimenti.
+    """
+    nz = np.nonzero(row)[0]
+    coeffs = row[nz]
+    all_binary = bool(np.all(integer_mask[nz]))
+    if all_binary and len(nz) > 2 and np.all(np.abs(np.abs(coeffs) - 1.0) < 1e-12):
+        if np.all(coeffs > 0) and abs(rhs - 1.0) < 1e-12:
+            return 'set_packing'
+        if np.all(coeffs < 0) and abs(rhs + 1.0) < 1e-12:
+            return 'set_covering'
+    if len(nz) == 2 and np.any(integer_mask[nz]):
+        return 'variable_bound'
+    if all_binary and np.all(coeffs > 0):
+        return 'knapsack'
+    return 'general'
+
+
+def matrix_stats(c: np.ndarray, A: np.ndarray, b: np.ndarray, integer_mask=None):
+    """
+    Statistiche del problema min c x, A x <= b (rappresentazione usata dal ciclo dei tagli):
+    dimensioni, densità, istogramma della magnitudine dei coefficienti (per potenze di 10),
+    singleton di riga e colonna, e classi di vincoli riconosciute.
+    Le coppie di righe a x <= 1, -a x <= -1 vengono riconosciute come un unico vincolo
+    di set partitioning (a x = 1).
+    """
+    n_rows, n_cols = A.shape
+    integer_mask = np.ones(n_cols, dtype=bool) if integer_mask is None else np.asarray(integer_mask, dtype=bool)
+    nonzero = A != 0
+    nnz = int(nonzero.sum())
+
+    magnitudes = np.abs(A[nonzero])
+    histogram = {}
+    for exponent in np.floor(np.log10(magnitudes)).astype(int) if nnz else []:
+        key = f"1e{exponent}"
+        histogram[key] = histogram.get(key, 0) + 1
+    histogram = dict(sorted(histogram.items(), key=lambda item: int(item[0][2:])))
+
+    classes = {}
+    row_keys = {tuple(A[i]) + (b[i],): i for i in range(n_rows)}
+    paired = set()
+    for i in range(n_rows):
+        if i in paired:
+            continue
+        mirror = row_keys.get(tuple(-A[i]) + (-b[i],))
+        row_class = _row_class(A[i], b[i], integer_mask)
+        if mirror is not None and mirror != i:
+            # a x <= b e -a x <= -b insieme sono un'uguaglianza
+            paired.update((i, mirror))
+            row_class = 'set_partitioning' if row_class in ('set_packing', 'set_covering') else 'equality'
+        classes[row_class] = classes.get(row_class, 0) + 1
+
+    row_counts = nonzero.sum(axis=1)
+    col_counts = nonzero.sum(axis=0)
+    nonzero_costs = np.abs(c[c != 0])
+    return {
+        'n_vars': n_cols,
+        'n_integer_vars': int(integer_mask.sum()),
+        'n_rows': n_rows,
+        'n_equality_pairs': len(paired) // 2,
+        'nnz': nnz,
+        'density': nnz / (n_rows * n_cols) if n_rows and n_cols else 0.0,
+        'coefficient_range': (float(magnitudes.min()), float(magnitudes.max())) if nnz else (0.0, 0.0),
+        'coefficient_histogram': histogram,
+        'objective_range': ((float(nonzero_costs.min()), float(nonzero_costs.max()))
+                            if len(nonzero_costs) else (0.0, 0.0)),
+        'rhs_range': (float(np.abs(b).min()), float(np.abs(b).max())) if n_rows else (0.0, 0.0),
+        'row_singletons': int((row_counts == 1).sum()),
+        'column_singletons': int((col_counts == 1).sum()),
+        'empty_rows': int((row_counts == 0).sum()),
+        'empty_columns': int((col_counts == 0).sum()),
+        'max_row_length': int(row_counts.max()) if n_rows else 0,
+        'max_column_length': int(col_counts.max()) if n_cols else 0,
+        'constraint_classes': classes,
+    }
+
+
+def model_stats(model: FacilityLocationModel):
+    """Statistiche della rappresentazione LP del modello UFL (vedi matrix_stats)."""
+    from algorithm.solver import Solver
+
+    c, A, b = Solver(model).get_problem_data()
+    return matrix_stats(c, A, b)
+
+
+def print_model_stats(stats: dict):
+    """Stampa un riepilogo leggibile delle statistiche del modello."""
+    print("\n" + "=" * 60)
+    print("STATISTICHE DEL MODELLO")
+    print("=" * 60)
+    print(f"Variabili: {stats['n_vars']} ({stats['n_integer_vars']} intere) | Righe: {stats['n_rows']} "
+          f"({stats['n_equality_pairs']} coppie di uguaglianza)")
+    print(f"Non zeri: {stats['nnz']} | Densità: {stats['density']:.4%}")
+    print(f"Coefficienti in [{stats['coefficient_range'][0]:g}, {stats['coefficient_range'][1]:g}] | "
+          f"Obiettivo in [{stats['objective_range'][0]:g}, {stats['objective_range'][1]:g}]")
+    print("Istogramma delle magnitudini: " +
+          ", ".join(f"{k}: {v}" for k, v in stats['coefficient_histogram'].items()))
+    print(f"Singleton di riga: {stats['row_singletons']} | di colonna: {stats['column_singletons']} | "
+          f"righe vuote: {stats['empty_rows']} | colonne vuote: {stats['empty_columns']}")
+    print(f"Lunghezza massima di riga: {stats['max_row_length']} | di colonna: {stats['max_column_length']}")
+    print("Classi di vincoli: " + ", ".join(f"{k}: {v}" for k, v in sorted(stats['constraint_classes'].items())))
+    print("=" * 60)
@@ -7,7 +7,8 @@ from pathlib import Path
 from mpl_toolkits.axes_grid1 import make_axes_locatable
 from matplotlib.patches import Patch
 
-from config import MAX_ITERATIONS
+from analysis.metrics import performance_profile
+from config import MAX_ITERATIONS, THRESHOLD_GAP
 
 plt.style.use('seaborn-v0_8-whitegrid')
 sns.set_palette('muted')
@@ -365,6 +366,41 @@ def save_summary_report(all_summaries: list[dict], output_dir: Path):
     print("...Grafici generati con successo.")
 
 
+def plot_performance_profile(df: pd.DataFrame, output_dir: Path, metric: str = 'total_time_ms'):
+    """
+    Crea il performance profile (Dolan-Moré) delle modalità di taglio sulla metrica indicata.
+    Le istanze con gap finale oltre THRESHOLD_GAP vengono considerate come fallimenti (rapporto infinito):
+    lo stato 'optimal' indica solo che l'ultimo LP è stato risolto, non che i tagli abbiano chiuso il gap.
+    """
+    if df.empty or metric not in df.columns:
+        print(f"Dati insufficienti per il performance profile su '{metric}'.")
+        return
+
+    df_plot = df.copy()
+    df_plot['solved'] = df_plot['final_gap'] <= THRESHOLD_GAP
+    profile = performance_profile(df_plot, metric=metric, solved_col='solved')
+
+    fig, ax = plt.subplots(figsize=(12, 7))
+    for mode, group in profile.groupby('cut_mode'):
+        ax.step(group['tau'], group['rho'], where='post', label=mode)
+
+    ax.set_xscale('log')
+    ax.set_ylim(0, 1.05)
+    ax.set_title(f'Performance Profile delle Modalità di Taglio ({metric})', fontsize=16, fontweight='bold')
+    ax.set_xlabel('Fattore di prestazione τ (scala logaritmica)', fontsize=12)
+    ax.set_ylabel('Frazione di istanze ρ(τ)', fontsize=12)
+    ax.legend(title='Modalità')
+    plt.tight_layout()
+
+    output_dir.mkdir(parents=True, exist_ok=True)
+    csv_path = output_dir / f"_performance_profile_{metric}.csv"
+    profile.to_csv(csv_path, index=False)
+    plot_path = output_dir / f"_performance_profile_{metric}.png"
+    plt.savefig(plot_path, dpi=300)
+    plt.close(fig)
+    print(f"Performance profile su '{metric}' salvato in: {plot_path}")
+
+
 def plot_combined_summary(csv_path: Path):
     """
     Crea un grafico riassuntivo che mostra le performance combinate
@@ -0,0 +1,110 @@
+import json
+from pathlib import Path
+
+import numpy as np
+
+from utility.facilityLocation import FacilityLocationModel
+
+
+def build_incidence_graph(A: np.ndarray, var_names=None, row_names=None):
+    """
+    Costruisce il grafo bipartito variabili-vincoli della matrice A:
+    un arco (i, j) per ogni coefficiente non nullo A[i][j].
+    """
+    n_rows, n_cols = A.shape
+    var_names = var_names or [f"x{j}" for j in range(n_cols)]
+    row_names = row_names or [f"c{i}" for i in range(n_rows)]
+
+    rows_idx, cols_idx = np.nonzero(A)
+    edges = [(int(i), int(j), float(A[i, j])) for i, j in zip(rows_idx, cols_idx)]
+
+    return {
+        'variables': list(var_names),
+        'constraints': list(row_names),
+        'edges': edges,
+    }
+
+
+def facility_blocks(model: FacilityLocationModel):
+    """
+    Raggruppa le variabili UFL per facility: il blocco u contiene x_u e tutte le y_uv.
+    I vincoli di assegnamento dei clienti sono i vincoli "di collegamento" tra blocchi.
+    """
+    p = model.get_num_facilities()
+    r = model.get_num_customers()
+
+    clusters = {u: f"facility_{u}" for u in range(p)}
+    for u in range(p):
+        for v in range(r):
+            clusters[p + u * r + v] = f"facility_{u}"
+    return clusters
+
+
+def export_incidence_dot(graph: dict, output_file: Path, clusters: dict = None):
+    """
+    Esporta il grafo di incidenza in formato DOT (Graphviz).
+    Se clusters è fornito (indice variabile -> etichetta), le variabili vengono raggruppate in subgraph.
+    """
+    output_file = Path(output_file)
+    output_file.parent.mkdir(parents=True, exist_ok=True)
+
+    lines = ["graph incidence {", "  rankdir=LR;", "  node [fontsize=9];"]
+
+    # Nodi vincolo (rettangoli)
+    for i, name in enumerate(graph['constraints']):
+        lines.append(f'  r{i} [label="{name}", shape=box, style=filled, fillcolor=lightyellow];')
+
+    # Nodi variabile (cerchi), eventualmente raggruppati per blocco
+    if clusters:
+        by_cluster = {}
+        for j in range(len(graph['variables'])):
+            by_cluster.setdefault(clusters.get(j, "unassigned"), []).append(j)
+        for k, (label, members) in enumerate(by_cluster.items()):
+            lines.append(f'  subgraph cluster_{k} {{')
+            lines.append(f'    label="{label}";')
+            for j in members:
+                lines.append(f'    v{j} [label="{graph["variables"][j]}", shape=ellipse];')
+            lines.append("  }")
+    else:
+        for j, name in enumerate(graph['variables']):
+            lines.append(f'  v{j} [label="{name}", shape=ellipse];')
+
+    for i, j, _ in graph['edges']:
+        lines.append(f"  r{i} -- v{j};")
+    lines.append("}")
+
+    with open(output_file, "w") as f:
+        f.write("\n".join(lines) + "\n")
+    print(f"Grafo di incidenza (DOT) salvato in: {output_file}")
+
+
+def export_incidence_json(graph: dict, output_file: Path, clusters: dict = None):
+    """Esporta il grafo di incidenza in JSON (nodi, archi e blocchi opzionali)."""
+    output_file = Path(output_file)
+    output_file.parent.mkdir(parents=True, exist_ok=True)
+
+    data = {
+        'variables': [
+            {'id': j, 'name': name, 'block': clusters.get(j) if clusters else None}
+            for j, name in enumerate(graph['variables'])
+        ],
+        'constraints': [{'id': i, 'name': name} for i, name in enumerate(graph['constraints'])],
+        'edges': [{'constraint': i, 'variable': j, 'coeff': coeff} for i, j, coeff in graph['edges']],
+    }
+
+    with open(output_file, "w") as f:
+        json.dump(data, f, indent=2)
+    print(f"Grafo di incidenza (JSON) salvato in: {output_file}")
+
+
+def export_model_structure(model: FacilityLocationModel, output_dir: Path, name: str, clustered=True):
+    """Esporta la struttura del modello UFL (DOT e JSON), opzionalmente raggruppata per facility."""
+    from algorithm.solver import Solver
+
+    _, A, _ = Solver(model).get_problem_data()
+    graph = build_incidence_graph(A)
+    clusters = facility_blocks(model) if clustered else None
+
+    export_incidence_dot(graph, Path(output_dir) / f"structure_{name}.dot", clusters)
+    export_incidence_json(graph, Path(output_dir) / f"structure_{name}.json", clusters)
+    return graph
@@ -7,6 +7,11 @@ DATA_DIR = PROJECT_ROOT / "data" / "instances"
 RESULTS_DIR = PROJECT_ROOT / "results"
 CONFIG_DIR = PROJECT_ROOT / "config"
 MODEL_DIR = PROJECT_ROOT / "model"
+SOLUTIONS_DIR = RESULTS_DIR / "solutions"
+PROFILES_DIR = RESULTS_DIR / "profiles"
+EVENT_LOGS_DIR = RESULTS_DIR / "events"
+SOLVER_CONFIG_FILE = PROJECT_ROOT / "solver.ini"
+HISTORY_DB = RESULTS_DIR / "history.sqlite"
 
 
 
@@ -15,3 +20,25 @@ TIME_LIMIT = 3600  # secondi
 THRESHOLD_GAP = 1e-5 # tolleranza per i risultati
 MAX_ITERATIONS = 10
 NUMERICAL_TOLERANCE = 1e-5
+DIVING_TIME_LIMIT = 2  # secondi per singolo tuffo delle euristiche di diving
+# Budget di tempo per fase (secondi, 0 = nessun limite oltre TIME_LIMIT)
+PRESOLVE_TIME_LIMIT = 0
+ROOT_CUT_TIME = 0  # ciclo dei tagli sul rilassamento della radice
+TREE_TIME_LIMIT = 0  # branch and bound dell'ILP di riferimento
+HEURISTIC_TIME_SHARE = 1.0  # frazione massima del tempo di solve spesa nelle euristiche primali
+HEURISTICS_FORCED = []  # euristiche da eseguire sempre, es. ['rounding_repair']
+HEURISTICS_DISABLED = []  # euristiche da non eseguire mai, es. ['dive_coefficient']
+CUT_MIN_EFFICACY = 1e-4  # efficacia minima (distanza dal punto LP) per aggiungere un taglio
+CUT_MAX_AGE = 3  # iterazioni consecutive non attive dopo cui un taglio viene rimosso
+REDUCED_COST_FIXING = False  # fissa le variabili tramite i costi ridotti rispetto all'incumbent
+DEBUG_CHECK_SOLUTION = False  # verifica le soluzioni vincolo per vincolo e riporta le violazioni
+RECORD_HISTORY = False  # registra ogni solve nell'archivio storico HISTORY_DB
+PROFILE_CPU = False  # salva un profilo CPU (cProfile) di ogni solve in PROFILES_DIR
+PROFILE_HEAP = False  # salva le righe che allocano più memoria (tracemalloc) di ogni solve in PROFILES_DIR
+EXACT_PRESOLVE = False  # costi letti come frazioni e presolve in aritmetica esatta prima dell'LP
+GUB_BRANCHING = False  # l'ILP di riferimento ramifica sulle righe sum y_uv = 1 come insiemi SOS1
+BRANCHING_RULE = 'auto'  # regola di branching dell'ILP di riferimento (vedi algorithm/branching.py)
+STRONG_CANDIDATES = 10  # candidati valutati dallo strong branching ristretto
+STRONG_ITERATIONS = 0  # iterazioni del simplesso per candidato (0 = automatico)
+NODE_SELECTION = 'auto'  # selezione dei nodi dell'ILP di riferimento (vedi algorithm/branching.py)
+NODE_BACKTRACK = 0.9999  # tolleranza di backtracking della selezione 'hybrid'
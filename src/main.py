@@ -3,10 +3,27 @@ import sys
 from utility.utils import *
 from algorithm.gomory import *
 from analysis.reporting import *
-from config import DATA_DIR, RESULTS_DIR
-
-
-CUT_MODES_AVAILABLE = ['GFC', 'GMI', 'BEST']
+from analysis.metrics import primal_dual_integral, trajectory_from_stats
+from utility.solutionWriter import write_solution
+from utility.solutionDiff import read_solution_sol, read_solution_instance, diff_solutions, print_solution_diff
+from utility.profiling import SolveProfiler
+from algorithm.options import SolverOptions, CUT_MODES, load_options
+from algorithm.tuning import tune
+from utility.errors import OptionsError
+from utility.repl import run_repl
+from analysis.history import SolveHistory, print_history
+from config import DATA_DIR, RESULTS_DIR, SOLUTIONS_DIR, PROFILES_DIR, EVENT_LOGS_DIR, SOLVER_CONFIG_FILE, HISTORY_DB
+
+
+CUT_MODES_AVAILABLE = list(CUT_MODES)
+# Parametri del solver usati da tutte le risoluzioni (eventualmente caricati da file in main)
+solver_options = SolverOptions()
+# Cartella in cui salvare le soluzioni .sol/.csv, impostata con --out (None = non salvare)
+solution_out_dir = None
+# Cartella per i log degli eventi del solve in JSON lines, impostata con --events (None = nessun log)
+event_log_out_dir = None
+# Cartella per i profili CPU/heap di ogni solve, impostata con --profile (None = solo se richiesti dalle opzioni)
+profile_out_dir = None
 
 def categorize_solution(status, initial_gap, final_gap):
     """Determina la categoria di soluzione in base a stato e gap."""
@@ -40,28 +57,67 @@ def create_solution_summary(instance_name, mode, all_stats):
         'optimal_solution': initial_stats.get('optimal_ilp'),
         'initial_lp_solution': initial_stats.get('lp_solution'),
         'final_lp_solution': final_stats.get('lp_solution'),
+        'heuristic_solution': final_stats.get('heuristic_ub'),
 
         'gap_closure': initial_gap - final_gap,
         'total_cuts': final_stats.get('n_cuts', 0),
         'total_iterations': final_stats.get('iterations', 0),
         'total_time_ms': final_stats.get('elapsed_time', 0),
+        'primal_dual_integral': primal_dual_integral(trajectory_from_stats(all_stats)),
         'final_status': status,
-        'solution_category': category
+        'solution_category': category,
+        'diagnostics': '; '.join(s['failure'] for s in all_stats if s.get('failure')),
+        'max_constraint_violation': final_stats.get('max_constraint_violation')
     }
-def process_instance(file_path, mode, generate_plots=True):
-    """Elabora una singola istanza con una modalità specificata."""
+def process_instance(file_path, mode, generate_plots=True, out_dir=None, event_log_dir=None,
+                     profile_dir=None, options: SolverOptions = None):
+    """
+    Elabora una singola istanza con una modalità specificata.
+    Se out_dir non è None, la soluzione ILP di riferimento viene salvata in formato .sol e .csv.
+    Se event_log_dir non è None, gli eventi del solve vengono salvati come JSON lines.
+    I profili CPU/heap del solve (con i tempi per fase) vengono salvati se profile_cpu/profile_heap
+    sono attivi nelle opzioni, in profile_dir o in PROFILES_DIR/<istanza>; un profile_dir indicato
+    senza alcuna delle due opzioni attiva entrambi i profili.
+    options sostituisce i parametri globali del solver (solver_options) per questa istanza.
+    """
     instance_name = file_path.stem
     print(f"\n-> Elaborazione: {instance_name} [Modalità: {mode}]")
 
+    event_log = None
     try:
-        model = FacilityLocationModel.from_file(file_path)
-        gomory_solver = Gomory(model)
-        all_stats = gomory_solver.solve_problem(str(file_path), cut_mode=mode)
+        options = options if options is not None else solver_options
+        model = FacilityLocationModel.from_file(file_path, exact=options.exact_presolve)
+        if event_log_dir is not None:
+            Path(event_log_dir).mkdir(parents=True, exist_ok=True)
+            event_log = open(Path(event_log_dir) / f"{instance_name}_{mode}.jsonl", "w")
+        # I tempi per fase si raccolgono sempre (per l'archivio storico); CPU e heap solo se richiesti
+        cpu, heap = options.profile_cpu, options.profile_heap
+        if profile_dir is not None and not (cpu or heap):
+            cpu = heap = True
+        if (cpu or heap) and profile_dir is None:
+            profile_dir = PROFILES_DIR / instance_name
+        profiler = SolveProfiler(cpu=cpu, heap=heap)
+        gomory_solver = Gomory(model, event_log=event_log, profiler=profiler, options=options)
+        profiler.start()
+        try:
+            all_stats = gomory_solver.solve_problem(str(file_path), cut_mode=mode)
+        finally:
+            profiler.stop()
+            if profile_dir is not None:
+                written = profiler.dump(profile_dir, f"{instance_name}_{mode}")
+                print(f"--> Profili salvati in: {Path(profile_dir)} ({len(written)} file)")
 
         if not all_stats:
             print(f"Nessuna statistica per {instance_name} in modalità {mode}.")
             return None
 
+        if gomory_solver.solver.violation_report is not None:
+            all_stats[-1]['max_constraint_violation'] = gomory_solver.solver.violation_report['max_constraint_violation']
+
+        if out_dir is not None:
+            write_solution(out_dir, instance_name, all_stats[0].get('optimal_ilp'), gomory_solver.solver.optimal_values,
+                           label=mode)
+
         # Genera grafici se richiesto e se abbiamo tagli
         if generate_plots and len(all_stats) > 1:
             print(f"--> L'istanza ha richiesto tagli. Genero grafico di convergenza.")
@@ -72,12 +128,19 @@ def process_instance(file_path, mode, generate_plots=True):
         elif len(all_stats) == 1:
             print(f"--> L'istanza è stata risolta al rilassamento LP iniziale. Grafico di convergenza non necessario.")
 
-        return create_solution_summary(instance_name, mode, all_stats)
+        summary = create_solution_summary(instance_name, mode, all_stats)
+        if options.record_history:
+            with SolveHistory(HISTORY_DB) as history:
+                history.record(model, summary, options, timings=profiler.summary())
+        return summary
 
     except Exception as e:
         print(f"\U0001F6AB Errore nell'elaborazione di {instance_name}: {e}")
         traceback.print_exc()
         return None
+    finally:
+        if event_log is not None:
+            event_log.close()
 
 def print_menu():
     print("\n" + "=" * 60)
@@ -87,7 +150,9 @@ def print_menu():
     print("2. Risolvi singola istanza esistente")
     print("3. Genera TUTTE le istanze UFL da config.ini")
     print("4. Risolvi le istanza UFL in tutte le modalità")
-    print("5. Esci")
+    print("5. Confronta due soluzioni salvate")
+    print("6. Tuning automatico dei parametri del solver")
+    print("7. Esci")
     print("=" * 60)
 
 
@@ -128,11 +193,15 @@ def process_single_instance_interactive():
             print("Selezione modalità non valida.")
             return
 
+        profile = input("Salvare i profili CPU/memoria del solve? (s/N): ").strip().lower() == 's'
+        profile_dir = PROFILES_DIR / instance_name if profile else profile_out_dir
+
         # 3. Esecuzione e raccolta risultati
         all_summaries = []
         for mode in modes_to_run:
             print(f"\n-> Esecuzione su {instance_name} [Modalità: {mode}]")
-            summary = process_instance(selected_file, mode)
+            summary = process_instance(selected_file, mode, out_dir=solution_out_dir,
+                                       event_log_dir=event_log_out_dir, profile_dir=profile_dir)
             if summary:
                 all_summaries.append(summary)
 
@@ -154,6 +223,48 @@ def process_single_instance_interactive():
         print(f"\U0001F6AB Errore critico durante l'elaborazione interattiva: {e}")
         traceback.print_exc()
 
+def compare_solution_files(first, second, instance_path=None):
+    """
+    Stampa il confronto strutturato tra due file .sol. Se instance_path non è indicato, il modello
+    di riferimento è l'istanza nominata nell'intestazione della prima soluzione (o nel nome del file).
+    """
+    first, second = Path(first), Path(second)
+    if instance_path is None:
+        instance_name = read_solution_instance(first) or first.stem
+        instance_files = list(DATA_DIR.rglob(f"{instance_name}.txt"))
+        if not instance_files:
+            print(f"Istanza '{instance_name}' non trovata in {DATA_DIR}.")
+            return
+        instance_path = instance_files[0]
+    model = FacilityLocationModel.from_file(instance_path)
+
+    _, values_a = read_solution_sol(first)
+    _, values_b = read_solution_sol(second)
+    print_solution_diff(diff_solutions(values_a, values_b, model))
+
+
+def compare_solutions_interactive():
+    """Permette di scegliere due file .sol salvati e ne stampa il confronto strutturato."""
+    sol_files = sorted(list(SOLUTIONS_DIR.rglob('*.sol')))
+    if len(sol_files) < 2:
+        print(f"Servono almeno due file .sol in {SOLUTIONS_DIR}.")
+        return
+
+    for i, file_path in enumerate(sol_files, 1):
+        print(f"{i}. {file_path.relative_to(SOLUTIONS_DIR)}")
+
+    try:
+        choices = [int(input(f"Seleziona la {which} soluzione (1-{len(sol_files)}): ")) - 1
+                   for which in ("prima", "seconda")]
+    except ValueError:
+        print("Input non valido.")
+        return
+    if not all(0 <= choice < len(sol_files) for choice in choices):
+        print("Selezione non valida.")
+        return
+    compare_solution_files(sol_files[choices[0]], sol_files[choices[1]])
+
+
 def process_all_instances_for_one_mode(mode: str):
     """
     Funzione cuore che elabora tutte le istanze per UNA SOLA modalità di taglio
@@ -169,7 +280,8 @@ def process_all_instances_for_one_mode(mode: str):
 
     all_summaries = []
     for file_path in txt_files:
-        summary = process_instance(file_path, mode)
+        summary = process_instance(file_path, mode, out_dir=solution_out_dir, event_log_dir=event_log_out_dir,
+                                   profile_dir=profile_out_dir)
         if summary:
             all_summaries.append(summary)
 
@@ -200,7 +312,8 @@ def process_all_instances_all_modes():
         # Per ogni istanza, cicla attraverso le modalità
         for mode in CUT_MODES_AVAILABLE:
             print(f"\n---> Esecuzione in modalità: {mode}")
-            summary = process_instance(file_path, mode)
+            summary = process_instance(file_path, mode, out_dir=solution_out_dir, event_log_dir=event_log_out_dir,
+                                       profile_dir=profile_out_dir)
             if summary:
                 all_runs_summaries.append(summary)
 
@@ -214,13 +327,66 @@ def process_all_instances_all_modes():
         df_all_runs.to_csv(csv_path, index=False)
         print(f"\n\nReport CSV completo di tutte le modalità salvato in: {csv_path}")
         plot_combined_summary(csv_path)
+        plot_performance_profile(df_all_runs, report_dir, metric='total_time_ms')
+        plot_performance_profile(df_all_runs, report_dir, metric='primal_dual_integral')
 
 
 
-def main():
+def tune_interactive():
+    """Esegue il tuning dei parametri su un cluster di istanze e salva la configurazione migliore."""
+    clusters = sorted(d for d in DATA_DIR.iterdir() if d.is_dir()) if DATA_DIR.exists() else []
+    if not clusters:
+        print("Nessun cluster di istanze trovato.")
+        return
+    for i, cluster in enumerate(clusters, 1):
+        print(f"{i}. {cluster.name}")
+    try:
+        choice = int(input(f"Seleziona il cluster su cui eseguire il tuning (1-{len(clusters)}): ")) - 1
+        if not (0 <= choice < len(clusters)):
+            print("Selezione non valida.")
+            return
+        n_trials = int(input("Numero di configurazioni da provare [10]: ").strip() or 10)
+    except ValueError:
+        print("Input non valido.")
+        return
+
+    instance_files = sorted(clusters[choice].glob('*.txt'))
+    output_file = RESULTS_DIR / "tuning" / f"{clusters[choice].name}_solver.ini"
+    best_options, best_score, _ = tune(instance_files, n_trials=n_trials, base=solver_options, output_file=output_file)
+    print(f"\nMigliore configurazione: {best_options}")
+    print(f"Gap medio {best_score[0]:.6f}, tempo medio {best_score[1]:.0f} ms.")
+    print(f"Per usarla: python main.py {output_file}")
+
+
+def load_solver_options(config_file=None):
+    """
+    Parametri del solver letti da config_file (o da SOLVER_CONFIG_FILE, se esiste);
+    in caso di errori o file mancante si usano i default.
+    """
+    config_file = Path(config_file) if config_file is not None else SOLVER_CONFIG_FILE
+    if config_file.exists():
+        try:
+            options = load_options(config_file)
+            print(f"Parametri del solver caricati da {config_file}.")
+            return options
+        except OptionsError as e:
+            print(f"\U0001F6AB {e}\nSi usano i parametri di default.")
+    elif config_file != SOLVER_CONFIG_FILE:
+        print(f"\U0001F6AB File di configurazione '{config_file}' non trovato. Si usano i parametri di default.")
+    return SolverOptions()
+
+
+def main(config_file=None):
+    """
+    Menu interattivo. I parametri del solver vengono letti da config_file
+    (o da SOLVER_CONFIG_FILE, se esiste); in caso di errori si usano i default.
+    """
+    global solver_options
+    solver_options = load_solver_options(config_file)
+
     while True:
         print_menu()
-        choice = input("Scegli un'opzione (1-5): ").strip()
+        choice = input("Scegli un'opzione (1-7): ").strip()
 
         if choice == '1':
             print("\n--- AVVIO RISOLUZIONE DI TUTTE LE ISTANZE ESISTENTI ---")
@@ -253,6 +419,14 @@ def main():
             process_all_instances_all_modes()
 
         elif choice == '5':
+            print("\n--- CONFRONTO TRA DUE SOLUZIONI ---")
+            compare_solutions_interactive()
+
+        elif choice == '6':
+            print("\n--- TUNING AUTOMATICO DEI PARAMETRI ---")
+            tune_interactive()
+
+        elif choice == '7':
             print("Arrivederci!")
             sys.exit()
 
@@ -263,6 +437,45 @@ def main():
 
 
 
+def pop_flag(args, flag, default):
+    """Rimuove da args l'opzione flag con il suo valore (facoltativo, altrimenti default); None se assente."""
+    if flag not in args:
+        return None
+    position = args.index(flag)
+    args.pop(position)
+    if position < len(args) and not args[position].startswith('--'):
+        return Path(args.pop(position))
+    return default
+
+
 if __name__ == "__main__":
     RESULTS_DIR.mkdir(parents=True, exist_ok=True)
-    main()
\ No newline at end of file
+    # Uso: python main.py [file_parametri_solver.ini] [--out [cartella]]
+    #                      [--events [cartella]] [--profile [cartella]]
+    #      (--out salva le soluzioni .sol/.csv, di default in results/solutions;
+    #       --events salva gli eventi di ogni solve in JSON lines, di default in results/events;
+    #       --profile salva i profili CPU/heap di ogni solve, di default in results/profiles)
+    #      python main.py tune <cartella_istanze> [numero_prove] [file_output.ini] [--config file_parametri.ini]
+    #      (il tuning parte dai parametri di --config, di default solver.ini se esiste)
+    #      python main.py repl <file_istanza>
+    #      python main.py history [nome_istanza] [numero_righe]
+    #      python main.py diff <a.sol> <b.sol> [file_istanza]
+    solution_out_dir = pop_flag(sys.argv, '--out', SOLUTIONS_DIR)
+    event_log_out_dir = pop_flag(sys.argv, '--events', EVENT_LOGS_DIR)
+    tune_config_file = pop_flag(sys.argv, '--config', None)
+    profile_out_dir = pop_flag(sys.argv, '--profile', PROFILES_DIR)
+    if len(sys.argv) > 2 and sys.argv[1] == 'repl':
+        run_repl(sys.argv[2])
+    elif len(sys.argv) > 3 and sys.argv[1] == 'diff':
+        compare_solution_files(sys.argv[2], sys.argv[3], sys.argv[4] if len(sys.argv) > 4 else None)
+    elif len(sys.argv) > 1 and sys.argv[1] == 'history':
+        with SolveHistory(HISTORY_DB) as history:
+            print_history(history.query(instance=sys.argv[2] if len(sys.argv) > 2 else None,
+                                        limit=int(sys.argv[3]) if len(sys.argv) > 3 else 20))
+    elif len(sys.argv) > 2 and sys.argv[1] == 'tune':
+        tune(sorted(Path(sys.argv[2]).rglob('*.txt')),
+             n_trials=int(sys.argv[3]) if len(sys.argv) > 3 else 10,
+             base=load_solver_options(tune_config_file),
+             output_file=sys.argv[4] if len(sys.argv) > 4 else RESULTS_DIR / "tuning" / "solver.ini")
+    else:
+        main(sys.argv[1] if len(sys.argv) > 1 else None)
\ No newline at end of file
@@ -0,0 +1,5 @@
+import sys
+from pathlib import Path
+
+# i moduli del progetto si importano dalla cartella src, come in main.py
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent))
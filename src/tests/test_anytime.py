@@ -0,0 +1,69 @@
+import threading
+import time
+
+from algorithm.anytime import solve_stream
+
+# lo stream riceve gli incumbent del proprio solve, può annullarlo e poi si stacca dal Gomory
+
+
+class _FakeSolver:
+    aborter = None
+
+
+class _FakeGomory:
+    """Pubblica gli incumbent indicati e, se richiesto, attende l'annullamento come il ciclo dei tagli."""
+    def __init__(self, objectives, wait_for_cancel=False):
+        self.objectives = objectives
+        self.wait_for_cancel = wait_for_cancel
+        self.incumbent_listeners = []
+        self.aborter = None
+        self.solver = _FakeSolver()
+        self.published = threading.Event()
+
+    def add_incumbent_listener(self, listener):
+        self.incumbent_listeners.append(listener)
+        return self
+
+    def remove_incumbent_listener(self, listener):
+        if listener in self.incumbent_listeners:
+            self.incumbent_listeners.remove(listener)
+        return self
+
+    def solve_problem(self, instance_path, cut_mode=None):
+        self.solver.aborter = self.aborter
+        for objective in self.objectives:
+            for listener in self.incumbent_listeners:
+                listener(objective=objective, values={'x0': 1.0}, source='test', time_ms=0.0)
+        self.published.set()
+        while self.wait_for_cancel and not self.aborter.is_aborted():
+            time.sleep(0.001)
+        return [{'cancelled': self.aborter.is_aborted()}]
+
+
+def test_stream_yields_incumbents_and_result():
+    stream = solve_stream(_FakeGomory([10.0, 7.0]), "inst.txt")
+    assert [incumbent.objective for incumbent in stream] == [10.0, 7.0]
+    assert stream.result.result() == [{'cancelled': False}]
+
+
+def test_cancel_stops_the_solve():
+    gomory = _FakeGomory([5.0], wait_for_cancel=True)
+    stream = solve_stream(gomory, "inst.txt")
+    assert gomory.published.wait(5)
+    stream.cancel()
+    assert stream.join(5)
+    assert [incumbent.objective for incumbent in stream] == [5.0]
+    assert stream.result.result() == [{'cancelled': True}]
+
+
+def test_listener_and_aborter_are_detached_after_the_solve():
+    gomory = _FakeGomory([3.0])
+    first = solve_stream(gomory, "inst.txt")
+    list(first)
+    assert first.join(5)
+    assert gomory.incumbent_listeners == [] and gomory.aborter is None and gomory.solver.aborter is None
+    # Un secondo stream sullo stesso Gomory non alimenta più la coda del primo
+    gomory.objectives = [2.0]
+    second = solve_stream(gomory, "inst.txt")
+    assert [incumbent.objective for incumbent in second] == [2.0]
+    assert first.incumbents.empty()
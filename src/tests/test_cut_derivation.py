@@ -0,0 +1,102 @@
+from collections import namedtuple
+
+import pytest
+
+from algorithm.gomory import Gomory
+from utility.facilityLocation import FacilityLocationModel
+
+# derivazione dei coefficienti MIR e zero-half su righe piccole e fissate (senza risolvere LP)
+
+Row = namedtuple('Row', 'ind val')
+
+
+class _FakeLP:
+    """Espone solo i dati letti dai separatori: punto LP, nomi delle variabili e righe a x (senso) b."""
+    def __init__(self, values, rows, rhs, senses):
+        self.solution = self
+        self.variables = self
+        self.linear_constraints = self
+        self._values, self._rows, self._rhs, self._senses = values, rows, rhs, senses
+
+    def get_values(self):
+        return self._values
+
+    def get_names(self):
+        return [f"x{j}" for j in range(len(self._values))]
+
+    def get_rows(self):
+        return self._rows
+
+    def get_rhs(self):
+        return self._rhs
+
+    def get_senses(self):
+        return self._senses
+
+
+def gomory():
+    return Gomory(FacilityLocationModel(1, 1, [1], [[1]]))
+
+
+def test_mir_rounding_of_a_fixed_row():
+    # x0 + 0.75 x1 - t2 + 2 t3 <= 1.5 con delta = 1: f0 = 0.5
+    new_int, new_cont, rhs = gomory()._mir_round({0: 1.0, 1: 0.75}, {2: -1.0, 3: 2.0}, 1.5, 1.0)
+    assert new_int == pytest.approx({0: 1.0, 1: 0.5})
+    # La continua con coefficiente positivo viene scartata, quella negativa divisa per 1 - f0
+    assert new_cont == pytest.approx({2: -2.0})
+    assert rhs == 1
+
+
+def test_mir_rounding_with_scaling_factor():
+    # Divisa per delta = 2: beta / delta = 0.75, f0 = 0.75
+    new_int, new_cont, rhs = gomory()._mir_round({0: 1.0, 1: 0.75}, {2: -1.0}, 1.5, 2.0)
+    assert new_int == pytest.approx({0: 0.0, 1: 0.0})
+    assert new_cont == pytest.approx({2: -2.0})
+    assert rhs == 0
+
+
+def test_mir_rounding_skips_integral_rhs():
+    assert gomory()._mir_round({0: 1.0}, {}, 2.0, 1.0) is None
+
+
+def test_mir_cut_back_in_structural_space():
+    # Taglio x0 + 0.5 (1 - x1) - 2 t0 <= 1 con x1 complementata e t0 slack di x0 + x1 <= 2
+    rounded = ({0: 1.0, 1: 0.5}, {0: -2.0}, 1)
+    coeffs, rhs = gomory()._mir_to_structural(rounded, {1}, [Row([0, 1], [1.0, 1.0])], [2.0], ['L'], 2)
+    # x0 - 0.5 x1 - 2 (2 - x0 - x1) <= 1 - 0.5  ->  3 x0 + 1.5 x1 <= 4.5
+    assert coeffs == pytest.approx({0: 3.0, 1: 1.5})
+    assert rhs == pytest.approx(4.5)
+
+
+def test_mir_slack_of_greater_equal_row_changes_sign():
+    # t0 = x0 + x1 - 1 >= 0 per la riga x0 + x1 >= 1: -t0 <= 0 diventa -x0 - x1 <= -1
+    coeffs, rhs = gomory()._mir_to_structural(({}, {0: -1.0}, 0), set(), [Row([0, 1], [1.0, 1.0])],
+                                             [1.0], ['G'], 2)
+    assert coeffs == pytest.approx({0: -1.0, 1: -1.0})
+    assert rhs == pytest.approx(-1.0)
+
+
+def test_zero_half_closes_an_odd_cycle():
+    # Triangolo x0 + x1 <= 1, x1 + x2 <= 1, x0 + x2 <= 1 in x = (1/2, 1/2, 1/2):
+    # la somma divisa per 2 e arrotondata dà x0 + x1 + x2 <= 1, violato di 1/2
+    lp = _FakeLP([0.5, 0.5, 0.5],
+                 [Row([0, 1], [1.0, 1.0]), Row([1, 2], [1.0, 1.0]), Row([0, 2], [1.0, 1.0])],
+                 [1.0, 1.0, 1.0], ['L', 'L', 'L'])
+    cuts = gomory()._generate_zero_half_cuts(lp)
+    triangle = [cut for cut in cuts if sorted(cut['indices']) == ['x0', 'x1', 'x2']]
+    assert len(triangle) == 1
+    assert triangle[0]['coeffs'] == [1.0, 1.0, 1.0]
+    assert triangle[0]['rhs'] == 1.0 and triangle[0]['sense'] == 'L'
+    assert triangle[0]['violation'] == pytest.approx(0.5)
+
+
+def test_zero_half_ignores_rows_with_fractional_data():
+    lp = _FakeLP([0.5, 0.5], [Row([0, 1], [0.5, 1.0])], [1.0], ['L'])
+    assert gomory()._generate_zero_half_cuts(lp) == []
+
+
+def test_zero_half_derives_no_cut_from_a_satisfied_integer_point():
+    lp = _FakeLP([1.0, 0.0, 0.0],
+                 [Row([0, 1], [1.0, 1.0]), Row([1, 2], [1.0, 1.0]), Row([0, 2], [1.0, 1.0])],
+                 [1.0, 1.0, 1.0], ['L', 'L', 'L'])
+    assert gomory()._generate_zero_half_cuts(lp) == []
@@ -0,0 +1,71 @@
+from algorithm.cutPool import CutPool
+
+# un taglio rimosso per età resta escluso per un'attesa che raddoppia a ogni rimozione
+
+
+class _FakeLP:
+    """Slack costante per tutti i tagli: con slack non nullo invecchiano a ogni iterazione."""
+    def __init__(self, slack=1.0):
+        self.solution = self
+        self.linear_constraints = self
+        self.slack = slack
+        self.deleted = []
+
+    def get_linear_slacks(self, names):
+        return [self.slack] * len(names)
+
+    def delete(self, names):
+        self.deleted += names
+
+
+CUT = {'indices': ['x0', 'x1'], 'coeffs': [1.0, 1.0], 'rhs': 1.0, 'sense': 'L'}
+VALUES = {'x0': 0.8, 'x1': 0.8}
+
+
+def add(pool, name="cut_0"):
+    selected = pool.select([CUT], VALUES)
+    for cut in selected:
+        pool.register(name, cut)
+    return selected
+
+
+def purge(pool, lp, rounds):
+    removed = []
+    for _ in range(rounds):
+        removed += pool.age_and_purge(lp)
+    return removed
+
+
+def test_purged_cut_waits_cooldown_before_readd():
+    pool, lp = CutPool(max_age=2, purge_cooldown=3), _FakeLP()
+    assert add(pool)
+    assert purge(pool, lp, 2) == ['cut_0']
+    # Ancora violato, ma in attesa: escluso anche con efficacia ben sopra la soglia
+    for _ in range(2):
+        assert add(pool) == []
+        purge(pool, lp, 1)
+    assert add(pool) == []
+    assert pool.summary()['purged_rejected'] == 3
+    purge(pool, lp, 1)
+    assert add(pool)
+
+
+def test_cooldown_doubles_on_repeated_purges():
+    pool, lp = CutPool(max_age=1, purge_cooldown=2), _FakeLP()
+    add(pool)
+    purge(pool, lp, 1)
+    purge(pool, lp, 2)
+    assert add(pool)
+    assert purge(pool, lp, 1) == ['cut_0']
+    # Seconda rimozione: attesa di 4 iterazioni
+    purge(pool, lp, 3)
+    assert add(pool) == []
+    purge(pool, lp, 1)
+    assert add(pool)
+
+
+def test_active_cut_is_not_purged():
+    pool, lp = CutPool(max_age=2), _FakeLP(slack=0.0)
+    add(pool)
+    assert purge(pool, lp, 5) == []
+    assert pool.summary()['active_cuts'] == 1
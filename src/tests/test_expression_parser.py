@@ -0,0 +1,59 @@
+import pytest
+
+from utility.expressionParser import ConstraintParseError, parse_constraint
+from utility.facilityLocation import FacilityLocationModel
+
+
+def model():
+    return FacilityLocationModel(2, 3, [10, 20], [[1, 2], [3, 4], [5, 6]])
+
+
+def parse(text):
+    constraint = model().parse_constraint(text)
+    return dict(zip(constraint['indices'], constraint['coeffs'])), constraint['sense'], constraint['rhs']
+
+
+def test_linear_expression_with_all_variable_names():
+    # open[1] = x1, assign[0,2] = x2 + 0 * 3 + 2 = x4
+    assert parse("3 x0 + 2*open[1] - assign[0,2] <= 10") == ({0: 3.0, 1: 2.0, 4: -1.0}, 'L', 10.0)
+
+
+def test_parentheses_and_implicit_multiplication():
+    assert parse("2(open[0] + open[1]) >= 1") == ({0: 2.0, 1: 2.0}, 'G', 1.0)
+
+
+def test_terms_are_moved_to_the_left_and_merged():
+    assert parse("open[0] + 1 = open[1] + 3 - open[0]") == ({0: 2.0, 1: -1.0}, 'E', 2.0)
+
+
+def test_alternative_operators():
+    assert parse("open[0] =< 1")[1] == 'L'
+    assert parse("open[0] => 1")[1] == 'G'
+    assert parse("open[0] == 1")[1] == 'E'
+
+
+def test_create_missing_adds_unknown_variables():
+    names = {'a': 0}
+    constraint = parse_constraint("a + b <= 1", names.get, create_missing=lambda name: names.setdefault(name, len(names)))
+    assert constraint['indices'] == [0, 1] and names == {'a': 0, 'b': 1}
+
+
+@pytest.mark.parametrize("text, message", [
+    ("open[0] < 1", "strette"),
+    ("open[0] * open[1] <= 1", "non lineare"),
+    ("open[0] + <= 1", "inatteso"),
+    ("open[0] + open[1]", "operatore di confronto"),
+    ("(open[0] + 1 <= 2", r"\)"),
+    ("open[0] $ 1", "Carattere non valido"),
+    ("open[0] - open[0] <= 1", "non contiene variabili"),
+    ("open[0] <= 1 )", "inatteso"),
+])
+def test_invalid_constraints(text, message):
+    with pytest.raises(ConstraintParseError, match=message):
+        model().parse_constraint(text)
+
+
+def test_unknown_variable_reports_its_position():
+    with pytest.raises(ConstraintParseError, match="sconosciuta 'open\\[5\\]'") as info:
+        model().parse_constraint("open[0] + open[5] <= 1")
+    assert info.value.position == 10
@@ -0,0 +1,37 @@
+import pytest
+
+from analysis.metrics import gap_function, primal_dual_integral, primal_integral, trajectory_from_stats
+
+
+def test_gap_function():
+    assert gap_function(None, 5) == 1.0
+    assert gap_function(10, 10) == 0.0
+    assert gap_function(10, -2) == 1.0
+    assert gap_function(10, 8) == pytest.approx(0.2)
+
+
+def test_primal_dual_integral_on_hand_computed_trajectory():
+    trajectory = [{'time': 1, 'primal': 10, 'dual': 5},
+                  {'time': 3, 'primal': 10, 'dual': 8},
+                  {'time': 4, 'primal': 10, 'dual': 10}]
+    # [0, 1): nessun evento, gap 1; [1, 3): 0.5; [3, 4): 0.2; [4, 6): 0
+    assert primal_dual_integral(trajectory, end_time=6) == pytest.approx(1 + 2 * 0.5 + 0.2)
+    # Senza end_time l'integrale si ferma all'ultimo evento
+    assert primal_dual_integral(trajectory) == pytest.approx(2.2)
+
+
+def test_primal_integral_without_incumbent_counts_full_gap():
+    trajectory = [{'time': 0, 'primal': None}, {'time': 2, 'primal': 12}, {'time': 5, 'primal': 10}]
+    # [0, 2): nessun incumbent, gap 1; [2, 5): 2/12
+    assert primal_integral(trajectory, optimal_value=10) == pytest.approx(2 + 3 * 2 / 12)
+
+
+def test_empty_trajectory_has_zero_integral():
+    assert primal_dual_integral([]) == 0.0
+
+
+def test_trajectory_from_stats_prefers_heuristic_and_rounded_bounds():
+    stats = [{'elapsed_time': 0, 'optimal_ilp': 10, 'lp_solution': 7.5, 'rounded_lp_bound': 8},
+             {'elapsed_time': 4, 'optimal_ilp': 10, 'heuristic_ub': 11, 'lp_solution': 9.2}]
+    assert trajectory_from_stats(stats) == [{'time': 0, 'primal': 10, 'dual': 8},
+                                            {'time': 4, 'primal': 11, 'dual': 9.2}]
@@ -0,0 +1,55 @@
+from utility.facilityLocation import FacilityLocationModel
+from utility.modelDiff import diff_models
+
+
+def base():
+    return FacilityLocationModel(2, 2, [10, 20], [[1, 2], [3, 4]])
+
+
+def test_identical_models_have_empty_diff():
+    diff = diff_models(base(), base())
+    assert all(not value for value in diff.values())
+
+
+def test_added_facility_reports_variables_with_names_and_labels():
+    a = base()
+    b = FacilityLocationModel(3, 2, [10, 20, 30], [[1, 2, 5], [3, 4, 6]])
+    diff = diff_models(a, b)
+    assert diff['added_facilities'] == [2]
+    # Nel modello b (p = 3, r = 2) x2 è open[2], x7 e x8 sono assign[2,0] e assign[2,1]
+    assert diff['added_variables'] == [{'name': 'x2', 'label': 'open[2]'},
+                                       {'name': 'x7', 'label': 'assign[2,0]'},
+                                       {'name': 'x8', 'label': 'assign[2,1]'}]
+    assert diff['added_constraints'] == ['link_2_0', 'link_2_1']
+    assert diff_models(b, a)['removed_variables'] == diff['added_variables']
+
+
+def test_modified_costs():
+    b = base()
+    b.fixed_costs[1] = 25
+    b.assignment_costs[0][1] = 7
+    diff = diff_models(base(), b)
+    assert diff['modified_fixed_costs'] == [{'facility': 1, 'old': 20, 'new': 25}]
+    assert diff['modified_assignment_costs'] == [{'facility': 1, 'customer': 0, 'old': 2, 'new': 7}]
+
+
+def test_side_constraints_are_compared_by_name():
+    a, b = base(), base()
+    a.add_constraint("open[0] + open[1] <= 1", name="cap")
+    a.add_constraint("open[0] >= 1", name="old")
+    b.add_constraint("open[0] + 2 open[1] >= 2", name="cap")
+    b.add_constraint("assign[1,1] <= 0", name="new")
+    diff = diff_models(a, b)
+    assert diff['added_side_constraints'] == ['new']
+    assert diff['removed_side_constraints'] == ['old']
+    assert diff['modified_side_constraints'] == [{'name': 'cap', 'sense': ('L', 'G'), 'rhs': (1.0, 2.0),
+                                                  'coeffs': {'open[1]': (1.0, 2.0)}}]
+
+
+def test_side_constraint_coefficients_follow_labels_across_layouts():
+    # assign[1,1] è x5 con p = 2 e x6 con p = 3: non è una modifica
+    a = base()
+    b = FacilityLocationModel(3, 2, [10, 20, 30], [[1, 2, 5], [3, 4, 6]])
+    a.add_constraint("assign[1,1] <= 0", name="cap")
+    b.add_constraint("assign[1,1] <= 0", name="cap")
+    assert diff_models(a, b)['modified_side_constraints'] == []
@@ -0,0 +1,95 @@
+from fractions import Fraction
+
+import pytest
+
+from algorithm.presolve import exact_presolve, exact_value
+from utility.errors import InfeasibleError
+
+
+def presolve(rows, rhs, n=2, lb=None, ub=None, **kwargs):
+    return exact_presolve([1] * n, rows, rhs, lb or [0] * n, ub or [5] * n, **kwargs)
+
+
+def test_exact_value_reads_decimal_representation():
+    assert exact_value(0.1) == Fraction(1, 10)
+    assert exact_value("3/7") == Fraction(3, 7)
+    assert exact_value("1e-3") == Fraction(1, 1000)
+
+
+def test_empty_row_is_removed():
+    result = presolve([{}, {0: 1, 1: 1}], [0, 1])
+    assert result.removed['empty_rows'] == 1
+    assert result.kept_rows == [1]
+
+
+def test_empty_row_with_negative_rhs_is_infeasible():
+    with pytest.raises(InfeasibleError):
+        presolve([{}], [-1])
+
+
+def test_singleton_row_becomes_rounded_bound():
+    # 2 x0 <= 3 con x0 intera -> x0 <= 1; -2 x1 <= -3 -> x1 >= 2
+    result = presolve([{0: 2}, {1: -2}], [3, -3])
+    assert result.removed['singleton_rows'] == 2
+    assert result.ub[0] == 1 and result.lb[1] == 2
+    assert result.kept_rows == []
+
+
+def test_singleton_row_on_continuous_column_keeps_fractional_bound():
+    result = presolve([{0: Fraction(2)}], [Fraction(3)], integer_mask=[False, False])
+    assert result.ub[0] == 1.5
+
+
+def test_incompatible_singleton_bounds_are_infeasible():
+    with pytest.raises(InfeasibleError):
+        presolve([{0: 1}, {0: -1}], [1, -2])
+
+
+def test_redundant_row_is_removed():
+    # x0 + x1 <= 10 è sempre soddisfatta con x in [0, 5]^2
+    result = presolve([{0: 1, 1: 1}, {0: 1, 1: 2}], [10, 4])
+    assert result.removed['redundant_rows'] == 1
+    assert result.kept_rows == [1]
+
+
+def test_parallel_rows_keep_the_tightest():
+    # x0 + 2 x1 <= 4 e 2 x0 + 4 x1 <= 6 (cioè x0 + 2 x1 <= 3): resta la seconda
+    result = presolve([{0: 1, 1: 2}, {0: 2, 1: 4}], [4, 6])
+    assert result.removed['parallel_rows'] == 1
+    assert result.kept_rows == [1]
+
+
+def test_parallel_rows_with_fractions_are_compared_exactly():
+    third = Fraction(1, 3)
+    result = presolve([{0: third, 1: third}, {0: 1, 1: 1}], [third, 1])
+    assert result.removed['parallel_rows'] == 1
+    assert len(result.kept_rows) == 1
+
+
+def test_nearly_parallel_rows_are_kept():
+    result = presolve([{0: 1, 1: 2}, {0: 1, 1: Fraction(2000001, 1000000)}], [4, 4])
+    assert result.removed['parallel_rows'] == 0
+    assert result.kept_rows == [0, 1]
+
+
+def test_opposite_rows_are_infeasible_when_incompatible():
+    # x0 + x1 <= 1 e x0 + x1 >= 3
+    with pytest.raises(InfeasibleError):
+        presolve([{0: 1, 1: 1}, {0: -1, 1: -1}], [1, -3])
+
+
+def test_postsolve_restores_aggregated_columns():
+    # x0 e x1 hanno stesso costo e stessa colonna: vengono sommate in [0, 2]
+    rows = [{0: -1, 1: -1, 2: -1}]
+    result = exact_presolve([1, 1, 2], rows, [Fraction(-3, 2)], [0, 0, 0], [1, 1, 1],
+                            integer_mask=[False] * 3, aggregate_columns=True)
+    assert result.removed['merged_columns'] == 1
+    assert result.kept_columns == [0, 2]
+    assert result.ub == [2.0, 1.0]
+    assert result.postsolve_values([1.5, 0.0]) == [1.0, 0.5, 0.0]
+
+
+def test_postsolve_duals_are_zero_on_removed_rows():
+    result = presolve([{}, {0: 1, 1: 2}, {0: 2}], [0, 4, 3])
+    assert result.kept_rows == [1]
+    assert result.postsolve_duals([-2.0]) == [0.0, -2.0, 0.0]
@@ -0,0 +1,57 @@
+from algorithm.presolve import exact_problem_rows
+from algorithm.solver import Solver
+from utility.facilityLocation import FacilityLocationModel
+
+# l'LP costruito riga per riga deve avere le righe di get_problem_data, nello stesso ordine
+
+
+class _FakeConstraints:
+    """Raccoglie le righe passate a linear_constraints.add, come farebbe CPLEX."""
+    def __init__(self):
+        self.linear_constraints = self
+        self.calls = 0
+        self.rows, self.rhs, self.senses, self.names = [], [], [], []
+
+    def add(self, lin_expr, rhs, senses, names):
+        self.calls += 1
+        self.rows += [dict(zip(pair.ind, pair.val)) for pair in lin_expr]
+        self.rhs += rhs
+        self.senses += senses
+        self.names += names
+
+
+def model():
+    m = FacilityLocationModel(2, 3, [10, 20], [[1, 2], [3, 4], [5, 6]])
+    m.add_constraint("open[0] + open[1] >= 1", name="at_least_one")
+    m.add_constraint("assign[0,0] - assign[1,2] = 0", name="linked")
+    return m
+
+
+def test_sparse_rows_match_problem_rows():
+    m = model()
+    rows, rhs = exact_problem_rows(m)
+    prob = _FakeConstraints()
+    n_rows = Solver(m).add_problem_rows(prob)
+    assert n_rows == len(rows) == len(prob.rows)
+    assert prob.rows == [{j: float(a) for j, a in row.items()} for row in rows]
+    assert prob.rhs == [float(value) for value in rhs]
+    assert set(prob.senses) == {'L'}
+    assert prob.names == [f"c{i}" for i in range(n_rows)]
+
+
+def test_rows_are_added_in_chunks():
+    solver = Solver(model())
+    prob = _FakeConstraints()
+    n_rows = solver.add_problem_rows(prob, chunk_rows=4)
+    assert prob.calls == -(-n_rows // 4)
+    assert prob.names == [f"c{i}" for i in range(n_rows)]
+
+
+def test_coefficients_become_floats():
+    m = FacilityLocationModel(1, 1, [1], [[1]])
+    m.add_constraint("2 open[0] <= 1", name="half")
+    prob = _FakeConstraints()
+    Solver(m).add_problem_rows(prob)
+    assert prob.rows[-1] == {0: 2.0} and prob.rhs[-1] == 1.0
+    assert all(isinstance(a, float) for row in prob.rows for a in row.values())
+    assert all(isinstance(value, float) for value in prob.rhs)
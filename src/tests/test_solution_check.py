@@ -0,0 +1,53 @@
+from utility.facilityLocation import FacilityLocationModel
+from utility.solutionCheck import check_solution
+
+# verifica vincolo per vincolo: righe UFL, vincoli aggiunti e variabili rilassate a continue
+
+
+def model():
+    return FacilityLocationModel(2, 1, [1, 1], [[1, 1]])
+
+
+def values(x0, x1, y00, y10):
+    # x_u all'indice u, y_uv all'indice p + u * r + v
+    return {'x0': x0, 'x1': x1, 'x2': y00, 'x3': y10}
+
+
+def test_integer_solution_is_feasible():
+    report = check_solution(model(), values(1.0, 0.0, 1.0, 0.0))
+    assert report['feasible']
+    assert report['max_constraint_violation'] == 0.0
+
+
+def test_violated_side_constraint_is_reported():
+    m = model()
+    m.add_constraint("open[0] + open[1] >= 2", name="both_open")
+    report = check_solution(m, values(1.0, 0.0, 1.0, 0.0))
+    assert not report['feasible']
+    assert [c['name'] for c in report['violated_constraints']] == ['both_open']
+    violated = report['violated_constraints'][0]
+    assert violated['group'] == 'side' and violated['sense'] == 'G'
+    assert violated['activity'] == 1.0 and violated['violation'] == 1.0
+
+
+def test_satisfied_side_constraint_is_listed():
+    m = model()
+    m.add_constraint("open[0] <= 1", name="cap")
+    report = check_solution(m, values(1.0, 0.0, 1.0, 0.0))
+    assert report['feasible']
+    assert any(c['name'] == 'cap' and c['violation'] == 0.0 for c in report['constraints'])
+
+
+def test_relaxed_groups_skip_integrality():
+    fractional = values(1.0, 0.0, 0.5, 0.5)
+    fractional['x1'] = 0.5
+    assert not check_solution(model(), fractional)['feasible']
+    report = check_solution(model(), fractional, relaxed_groups=('assignment', 'facility'))
+    assert report['feasible']
+    assert report['max_integrality_violation'] == 0.0
+
+
+def test_relaxed_groups_keep_integrality_elsewhere():
+    fractional = values(1.0, 0.5, 0.5, 0.5)
+    report = check_solution(model(), fractional, relaxed_groups=('assignment',))
+    assert [v['name'] for v in report['violated_variables']] == ['x1']
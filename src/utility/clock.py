@@ -0,0 +1,63 @@
+import time
+
+# orologi per limiti di tempo e misure: tempo reale, tempo virtuale (test) e unità di lavoro deterministiche
+
+
+class Clock:
+    """
+    Interfaccia per la misura del tempo: now() restituisce i secondi trascorsi da un'origine
+    arbitraria (monotona). charge(units) segnala lavoro svolto (iterazioni del simplesso):
+    gli orologi basati sul lavoro avanzano solo così, quelli reali lo ignorano.
+    """
+    def now(self):
+        raise NotImplementedError
+
+    def charge(self, units):
+        pass
+
+    def elapsed_ms(self, start):
+        return (self.now() - start) * 1000
+
+
+class WallClock(Clock):
+    """Tempo reale (time.monotonic); è l'orologio predefinito."""
+    def now(self):
+        return time.monotonic()
+
+
+class VirtualClock(Clock):
+    """
+    Tempo simulato per i test: avanza solo con advance(), oppure di `step` secondi
+    a ogni lettura di now(), così i limiti di tempo scattano in modo riproducibile.
+    """
+    def __init__(self, start=0.0, step=0.0):
+        self.time = float(start)
+        self.step = float(step)
+
+    def now(self):
+        current = self.time
+        self.time += self.step
+        return current
+
+    def advance(self, seconds):
+        self.time += seconds
+
+
+class WorkClock(Clock):
+    """
+    Orologio deterministico: il tempo è il lavoro accumulato con charge(), convertito in
+    secondi con seconds_per_unit. Due esecuzioni identiche misurano lo stesso tempo su
+    qualsiasi macchina, utile per confronti riproducibili in CI.
+    """
+    def __init__(self, seconds_per_unit=1e-3):
+        self.work = 0
+        self.seconds_per_unit = seconds_per_unit
+
+    def now(self):
+        return self.work * self.seconds_per_unit
+
+    def charge(self, units):
+        self.work += units
+
+
+WALL_CLOCK = WallClock()
@@ -0,0 +1,105 @@
+from dataclasses import dataclass
+
+import numpy as np
+
+from utility.dataBinding import DataBindingError
+from utility.facilityLocation import FacilityLocationModel
+
+# stima dei coefficienti di costo da dati storici con i minimi quadrati e calibrazione del modello UFL
+
+
+def least_squares(X, y, method='qr', ridge=0.0):
+    """
+    Risolve min ||X beta - y||^2 (+ ridge * ||beta||^2).
+    method='qr': X = QR e R beta = Q^T y, numericamente stabile anche con colonne quasi collineari;
+    method='normal': equazioni normali (X^T X + ridge I) beta = X^T y, più rapido ma meno stabile.
+    Con ridge > 0 il QR viene applicato alla matrice aumentata [X; sqrt(ridge) I].
+    """
+    X = np.asarray(X, dtype=np.float64)
+    y = np.asarray(y, dtype=np.float64)
+    if X.ndim != 2 or X.shape[0] != y.shape[0]:
+        raise ValueError(f"Dimensioni incompatibili: X {X.shape}, y {y.shape}")
+    n_features = X.shape[1]
+    if method == 'normal':
+        return np.linalg.solve(X.T @ X + ridge * np.eye(n_features), X.T @ y)
+    if method != 'qr':
+        raise ValueError(f"Metodo non supportato: {method} (usare 'qr' o 'normal')")
+    if ridge > 0:
+        X = np.vstack([X, np.sqrt(ridge) * np.eye(n_features)])
+        y = np.concatenate([y, np.zeros(n_features)])
+    if X.shape[0] < n_features:
+        raise ValueError(f"Servono almeno {n_features} osservazioni per stimare {n_features} coefficienti.")
+    Q, R = np.linalg.qr(X)
+    if np.min(np.abs(np.diag(R))) < 1e-12 * max(1.0, np.max(np.abs(np.diag(R)))):
+        raise ValueError("Matrice dei dati a rango incompleto: colonne linearmente dipendenti (usare ridge > 0).")
+    return np.linalg.solve(R, Q.T @ y)
+
+
+@dataclass
+class LinearFit:
+    """Modello lineare stimato: costo = intercept + sum_k coefficients[k] * feature_k."""
+    feature_names: list
+    coefficients: np.ndarray
+    intercept: float
+    r2: float
+    rmse: float
+    n_samples: int
+
+    def predict(self, features):
+        """Costo previsto per un dizionario nome feature -> valore (o una sequenza nell'ordine di feature_names)."""
+        if isinstance(features, dict):
+            features = [features[name] for name in self.feature_names]
+        return float(self.intercept + np.dot(self.coefficients, np.asarray(features, dtype=np.float64)))
+
+
+def fit_linear(rows, feature_cols, target_col, intercept=True, method='qr', ridge=0.0, source="dati"):
+    """
+    Stima un LinearFit da righe di dizionari (es. csv.DictReader o utility.dataBinding.rows_from_strings).
+    I valori non numerici vengono segnalati tutti insieme in un DataBindingError, come nel data binding.
+    """
+    X, y, details = [], [], []
+    for line, row in enumerate(rows, start=2):  # riga 1 = intestazione
+        try:
+            X.append([float(row[col]) for col in feature_cols])
+            y.append(float(row[target_col]))
+        except (KeyError, TypeError, ValueError) as e:
+            details.append(f"{source}, riga {line}: {type(e).__name__}: {e}")
+    if details:
+        raise DataBindingError(f"{len(details)} errori nei dati di stima:\n  - " + "\n  - ".join(details), details)
+    if not y:
+        raise ValueError(f"Nessuna osservazione in {source}.")
+
+    X, y = np.asarray(X, dtype=np.float64), np.asarray(y, dtype=np.float64)
+    design = np.hstack([np.ones((len(y), 1)), X]) if intercept else X
+    beta = least_squares(design, y, method, ridge)
+    residuals = y - design @ beta
+    total = float(np.sum((y - np.mean(y)) ** 2))
+    return LinearFit(
+        feature_names=list(feature_cols),
+        coefficients=beta[1:] if intercept else beta,
+        intercept=float(beta[0]) if intercept else 0.0,
+        r2=1.0 - float(np.sum(residuals ** 2)) / total if total > 0 else 1.0,
+        rmse=float(np.sqrt(np.mean(residuals ** 2))),
+        n_samples=len(y),
+    )
+
+
+def calibrate_assignment_costs(model: FacilityLocationModel, fit: LinearFit, features: dict, min_cost=0.0):
+    """
+    Sostituisce i costi di assegnamento con le previsioni del modello stimato:
+    features {(cliente, facility): {nome feature: valore}}. Le previsioni sotto min_cost
+    vengono troncate (un costo negativo renderebbe conveniente assegnare più del necessario).
+    Restituisce il numero di costi aggiornati.
+    """
+    model.check_mutable()
+    for (v, u), values in features.items():
+        model.assignment_costs[v][u] = max(min_cost, fit.predict(values))
+    return len(features)
+
+
+def calibrate_fixed_costs(model: FacilityLocationModel, fit: LinearFit, features: dict, min_cost=0.0):
+    """Come calibrate_assignment_costs per i costi fissi: features {facility: {nome feature: valore}}."""
+    model.check_mutable()
+    for u, values in features.items():
+        model.fixed_costs[u] = max(min_cost, fit.predict(values))
+    return len(features)
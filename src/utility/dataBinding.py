@@ -0,0 +1,124 @@
+import csv
+from pathlib import Path
+
+from utility.facilityLocation import FacilityLocationModel
+
+# costruzione di parametri indicizzati da CSV (o da righe di stringhe) e modelli UFL da dati tabellari
+
+
+class DataBindingError(ValueError):
+    """Errore di conversione dei dati; details contiene un messaggio per ogni riga problematica."""
+
+    def __init__(self, message, details=None):
+        super().__init__(message)
+        self.details = details or []
+
+
+def indexed_parameter(rows, index_cols, value_col, convert=float, index_convert=str, source="dati"):
+    """
+    Costruisce un parametro indicizzato {indice: valore} da righe di dizionari (es. csv.DictReader).
+    index_cols: nome della colonna indice o elenco di nomi (indice composto -> tupla).
+    convert/index_convert: conversione tipizzata di valori e indici.
+    Tutti gli errori (colonne mancanti, valori non convertibili, indici duplicati)
+    vengono raccolti e segnalati insieme con il numero di riga.
+    """
+    composite = not isinstance(index_cols, str)
+    index_cols = list(index_cols) if composite else [index_cols]
+
+    parameter, details = {}, []
+    for line, row in enumerate(rows, start=2):  # riga 1 = intestazione
+        missing = [col for col in index_cols + [value_col] if row.get(col) in (None, '')]
+        if missing:
+            details.append(f"{source}, riga {line}: colonne mancanti o vuote {missing}")
+            continue
+        try:
+            key = tuple(index_convert(row[col].strip()) for col in index_cols)
+            key = key if composite else key[0]
+            value = convert(row[value_col].strip())
+        except ValueError as e:
+            details.append(f"{source}, riga {line}: {e}")
+            continue
+        if key in parameter:
+            details.append(f"{source}, riga {line}: indice duplicato {key}")
+            continue
+        parameter[key] = value
+
+    if details:
+        raise DataBindingError(f"{len(details)} errori nella lettura di {source}:\n  - " + "\n  - ".join(details),
+                               details)
+    return parameter
+
+
+def rows_from_strings(table, header=None):
+    """Converte una tabella di stringhe (list[list[str]]) in righe di dizionari; la prima riga è l'intestazione se header è None."""
+    table = [list(row) for row in table]
+    if header is None:
+        header, table = table[0], table[1:]
+    return [dict(zip(header, row)) for row in table]
+
+
+def read_indexed_csv(path, index_cols, value_col, convert=float, index_convert=str, delimiter=','):
+    """Legge un parametro indicizzato da file CSV con intestazione (vedi indexed_parameter)."""
+    path = Path(path)
+    with open(path, 'r', newline='') as f:
+        return indexed_parameter(list(csv.DictReader(f, delimiter=delimiter)), index_cols, value_col,
+                                 convert, index_convert, source=path.name)
+
+
+def index_set(parameter, position=None):
+    """Insieme ordinato degli indici di un parametro (o della componente `position` di indici composti)."""
+    keys = parameter.keys() if position is None else (key[position] for key in parameter)
+    return sorted(set(keys))
+
+
+def join(left: dict, right: dict, how='inner'):
+    """
+    Unisce due parametri sullo stesso insieme di indici: {indice: (valore_sx, valore_dx)}.
+    how='inner' tiene gli indici comuni, 'left' tutti quelli di sinistra (None se manca a destra).
+    """
+    if how == 'inner':
+        return {key: (left[key], right[key]) for key in left if key in right}
+    if how == 'left':
+        return {key: (left[key], right.get(key)) for key in left}
+    raise ValueError(f"Tipo di join non supportato: {how} (usare 'inner' o 'left')")
+
+
+def ufl_from_parameters(fixed_costs: dict, assignment_costs: dict, default_cost=None):
+    """
+    Costruisce un FacilityLocationModel da parametri indicizzati per identificativo:
+    fixed_costs {facility: costo}, assignment_costs {(cliente, facility): costo}.
+    Gli identificativi vengono ordinati e mappati sugli indici 0..p-1 e 0..r-1.
+    Le coppie mancanti usano default_cost se indicato, altrimenti sono un errore.
+    Restituisce (modello, id delle facility, id dei clienti) nell'ordine degli indici.
+    """
+    facilities = sorted(fixed_costs)
+    default_cost = float(default_cost) if default_cost is not None else None
+    unknown = sorted({f for _, f in assignment_costs if f not in fixed_costs})
+    customers = index_set(assignment_costs, position=0)
+
+    details = [f"facility '{f}' presente nei costi di assegnamento ma senza costo fisso" for f in unknown]
+    matrix = []
+    for customer in customers:
+        row = []
+        for facility in facilities:
+            cost = assignment_costs.get((customer, facility), default_cost)
+            if cost is None:
+                details.append(f"costo di assegnamento mancante per cliente '{customer}', facility '{facility}'")
+            row.append(cost)
+        matrix.append(row)
+    if details:
+        raise DataBindingError(f"{len(details)} errori nei dati UFL:\n  - " + "\n  - ".join(details), details)
+
+    model = FacilityLocationModel(len(facilities), len(customers), [fixed_costs[f] for f in facilities], matrix)
+    return model, facilities, customers
+
+
+def ufl_from_csv(facilities_csv, assignments_csv, default_cost=None, delimiter=','):
+    """
+    Carica un'istanza UFL da due CSV:
+    - facilities_csv con colonne facility, fixed_cost;
+    - assignments_csv con colonne customer, facility, cost.
+    """
+    fixed_costs = read_indexed_csv(facilities_csv, 'facility', 'fixed_cost', delimiter=delimiter)
+    assignment_costs = read_indexed_csv(assignments_csv, ['customer', 'facility'], 'cost', delimiter=delimiter)
+    return ufl_from_parameters(fixed_costs, assignment_costs, default_cost)
@@ -0,0 +1,110 @@
+from enum import Enum
+
+# errori tipizzati e stati di risoluzione, per poter reagire all'esito senza confrontare stringhe
+
+
+class SolveStatus(Enum):
+    OPTIMAL = "optimal"
+    INFEASIBLE = "infeasible"
+    UNBOUNDED = "unbounded"
+    INFEASIBLE_OR_UNBOUNDED = "infeasible_or_unbounded"
+    LIMIT_WITH_SOLUTION = "limit_with_solution"
+    LIMIT_WITHOUT_SOLUTION = "limit_without_solution"
+    NUMERICAL_FAILURE = "numerical_failure"
+    ERROR = "error"
+
+    @property
+    def has_solution(self):
+        return self in (SolveStatus.OPTIMAL, SolveStatus.LIMIT_WITH_SOLUTION)
+
+
+# Codici di stato CPLEX (LP e MIP) raggruppati per esito
+_CPLEX_STATUS_MAP = {
+    1: SolveStatus.OPTIMAL, 101: SolveStatus.OPTIMAL, 102: SolveStatus.OPTIMAL,
+    3: SolveStatus.INFEASIBLE, 103: SolveStatus.INFEASIBLE,
+    2: SolveStatus.UNBOUNDED, 118: SolveStatus.UNBOUNDED,
+    4: SolveStatus.INFEASIBLE_OR_UNBOUNDED, 119: SolveStatus.INFEASIBLE_OR_UNBOUNDED,
+    # Limiti (iterazioni, tempo, nodi, soluzioni, memoria, interruzione) con soluzione disponibile
+    104: SolveStatus.LIMIT_WITH_SOLUTION, 105: SolveStatus.LIMIT_WITH_SOLUTION,
+    107: SolveStatus.LIMIT_WITH_SOLUTION, 111: SolveStatus.LIMIT_WITH_SOLUTION,
+    113: SolveStatus.LIMIT_WITH_SOLUTION,
+    # Limiti senza alcuna soluzione
+    10: SolveStatus.LIMIT_WITHOUT_SOLUTION, 11: SolveStatus.LIMIT_WITHOUT_SOLUTION,
+    13: SolveStatus.LIMIT_WITHOUT_SOLUTION, 106: SolveStatus.LIMIT_WITHOUT_SOLUTION,
+    108: SolveStatus.LIMIT_WITHOUT_SOLUTION, 112: SolveStatus.LIMIT_WITHOUT_SOLUTION,
+    114: SolveStatus.LIMIT_WITHOUT_SOLUTION,
+    # Problemi numerici
+    5: SolveStatus.NUMERICAL_FAILURE, 6: SolveStatus.NUMERICAL_FAILURE,
+    109: SolveStatus.NUMERICAL_FAILURE, 110: SolveStatus.NUMERICAL_FAILURE,
+    115: SolveStatus.NUMERICAL_FAILURE,
+}
+
+
+def status_from_cplex(code: int) -> SolveStatus:
+    """Converte un codice di stato CPLEX nello stato di risoluzione corrispondente."""
+    return _CPLEX_STATUS_MAP.get(code, SolveStatus.ERROR)
+
+
+class SolverError(Exception):
+    """Errore base di risoluzione; porta con sé lo stato che l'ha generato."""
+    status = SolveStatus.ERROR
+
+    def __init__(self, message, status: SolveStatus = None):
+        super().__init__(message)
+        if status is not None:
+            self.status = status
+
+
+class InfeasibleError(SolverError):
+    status = SolveStatus.INFEASIBLE
+
+
+class UnboundedError(SolverError):
+    status = SolveStatus.UNBOUNDED
+
+
+class InfeasibleOrUnboundedError(SolverError):
+    status = SolveStatus.INFEASIBLE_OR_UNBOUNDED
+
+
+class LimitReachedError(SolverError):
+    status = SolveStatus.LIMIT_WITHOUT_SOLUTION
+
+
+class NumericalFailureError(SolverError):
+    status = SolveStatus.NUMERICAL_FAILURE
+
+
+class ModelInvalidError(SolverError, ValueError):
+    """Dati del modello non validi; details contiene l'elenco dei problemi riscontrati."""
+
+    def __init__(self, message, details=None):
+        super().__init__(message, SolveStatus.ERROR)
+        self.details = details or []
+
+
+class OptionsError(ValueError):
+    """Parametri del solver non validi; details contiene l'elenco dei problemi riscontrati."""
+
+    def __init__(self, message, details=None):
+        super().__init__(message)
+        self.details = details or []
+
+
+class ModelFrozenError(TypeError):
+    """Tentativo di modificare un modello congelato con FacilityLocationModel.freeze()."""
+
+
+def error_for_status(status: SolveStatus, message: str) -> SolverError:
+    """Restituisce l'eccezione tipizzata corrispondente a uno stato senza soluzione."""
+    if status == SolveStatus.INFEASIBLE:
+        return InfeasibleError(message)
+    if status == SolveStatus.UNBOUNDED:
+        return UnboundedError(message)
+    if status == SolveStatus.INFEASIBLE_OR_UNBOUNDED:
+        return InfeasibleOrUnboundedError(message)
+    if status == SolveStatus.LIMIT_WITHOUT_SOLUTION:
+        return LimitReachedError(message)
+    if status == SolveStatus.NUMERICAL_FAILURE:
+        return NumericalFailureError(message)
+    return SolverError(message, status)
@@ -0,0 +1,156 @@
+import re
+
+# parser di vincoli lineari da stringa, es. "3 x0 + 2*open[1] - assign[0,2] <= 10"
+
+
+class ConstraintParseError(ValueError):
+    """Errore di sintassi in un vincolo; position è l'indice del carattere che ha causato l'errore."""
+
+    def __init__(self, message, text, position):
+        self.text = text
+        self.position = position
+        pointer = " " * position + "^"
+        super().__init__(f"{message} (posizione {position})\n  {text}\n  {pointer}")
+
+
+_TOKEN_RE = re.compile(r"""
+    (?P<space>\s+)
+  | (?P<number>(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)
+  | (?P<name>[A-Za-z_][A-Za-z0-9_]*(?:\[\s*\d+\s*(?:,\s*\d+\s*)*\])?)
+  | (?P<op><=|>=|==|=<|=>|[-+*()=<>])
+""", re.VERBOSE)
+
+_SENSES = {'<=': 'L', '=<': 'L', '>=': 'G', '=>': 'G', '=': 'E', '==': 'E'}
+
+
+def _tokenize(text):
+    tokens, position = [], 0
+    while position < len(text):
+        match = _TOKEN_RE.match(text, position)
+        if match is None:
+            raise ConstraintParseError(f"Carattere non valido '{text[position]}'", text, position)
+        if match.lastgroup != 'space':
+            tokens.append((match.lastgroup, match.group(), position))
+        position = match.end()
+    tokens.append(('end', '', len(text)))
+    return tokens
+
+
+class _Parser:
+    """
+    Parser a discesa ricorsiva per espressioni lineari:
+        vincolo  := espr ('<=' | '>=' | '=') espr
+        espr     := ['+'|'-'] termine (('+'|'-') termine)*
+        termine  := fattore ('*'? fattore)*       (al più un fattore non costante)
+        fattore  := numero | nome | '(' espr ')'
+    Un'espressione è rappresentata come (coefficienti per nome, costante).
+    """
+    def __init__(self, text):
+        self.text = text
+        self.tokens = _tokenize(text)
+        self.pos = 0
+
+    def peek(self):
+        return self.tokens[self.pos]
+
+    def take(self):
+        token = self.tokens[self.pos]
+        self.pos += 1
+        return token
+
+    def error(self, message, token=None):
+        token = token or self.peek()
+        raise ConstraintParseError(message, self.text, token[2])
+
+    def constraint(self):
+        left = self.expression()
+        kind, value, _ = self.peek()
+        if kind != 'op' or value not in _SENSES:
+            if value in ('<', '>'):
+                self.error("Disuguaglianze strette non supportate: usare <= o >=")
+            self.error("Atteso un operatore di confronto (<=, >=, =)")
+        self.take()
+        right = self.expression()
+        if self.peek()[0] != 'end':
+            self.error(f"Simbolo inatteso '{self.peek()[1]}'")
+
+        # Porta tutto a sinistra: (left - right) sense 0
+        coeffs = dict(left[0])
+        for name, a in right[0].items():
+            coeffs[name] = coeffs.get(name, 0.0) - a
+        return coeffs, _SENSES[value], right[1] - left[1]
+
+    def expression(self):
+        coeffs, constant = {}, 0.0
+        sign = 1.0
+        if self.peek()[1] in ('+', '-'):
+            sign = -1.0 if self.take()[1] == '-' else 1.0
+        while True:
+            term_coeffs, term_constant = self.term()
+            for name, a in term_coeffs.items():
+                coeffs[name] = coeffs.get(name, 0.0) + sign * a
+            constant += sign * term_constant
+            if self.peek()[1] not in ('+', '-'):
+                return coeffs, constant
+            sign = -1.0 if self.take()[1] == '-' else 1.0
+
+    def term(self):
+        start = self.peek()
+        coeffs, constant = self.factor()
+        while self.peek()[1] == '*' or self.peek()[0] in ('number', 'name') or self.peek()[1] == '(':
+            if self.peek()[1] == '*':
+                self.take()
+            other_coeffs, other_constant = self.factor()
+            if coeffs and other_coeffs:
+                self.error("Termine non lineare: prodotto di due variabili", start)
+            if coeffs:
+                coeffs, constant = {n: a * other_constant for n, a in coeffs.items()}, constant * other_constant
+            else:
+                coeffs = {n: a * constant for n, a in other_coeffs.items()}
+                constant = constant * other_constant
+        return coeffs, constant
+
+    def factor(self):
+        kind, value, _ = self.peek()
+        if kind == 'number':
+            self.take()
+            return {}, float(value)
+        if kind == 'name':
+            self.take()
+            return {re.sub(r"\s+", "", value): 1.0}, 0.0
+        if value == '(':
+            self.take()
+            result = self.expression()
+            if self.peek()[1] != ')':
+                self.error("Attesa ')'")
+            self.take()
+            return result
+        if kind == 'end':
+            self.error("Espressione incompleta")
+        self.error(f"Simbolo inatteso '{value}'")
+
+
+def parse_constraint(text, resolve, create_missing=None):
+    """
+    Analizza un vincolo lineare e restituisce {'indices', 'coeffs', 'sense', 'rhs'}.
+    resolve(nome) deve restituire l'indice della variabile o None se non esiste;
+    create_missing(nome), se fornita, crea la variabile mancante e ne restituisce l'indice
+    (altrimenti un nome sconosciuto è un errore). I coefficienti nulli vengono eliminati.
+    """
+    parser = _Parser(text)
+    coeffs, sense, rhs = parser.constraint()
+
+    by_index = {}
+    for name, a in coeffs.items():
+        index = resolve(name)
+        if index is None:
+            if create_missing is None:
+                position = next(t[2] for t in parser.tokens if t[0] == 'name' and re.sub(r"\s+", "", t[1]) == name)
+                raise ConstraintParseError(f"Variabile sconosciuta '{name}'", text, position)
+            index = create_missing(name)
+        by_index[index] = by_index.get(index, 0.0) + a
+
+    by_index = {j: a for j, a in by_index.items() if abs(a) > 1e-12}
+    if not by_index:
+        raise ConstraintParseError("Il vincolo non contiene variabili", text, 0)
+    return {'indices': list(by_index), 'coeffs': list(by_index.values()), 'sense': sense, 'rhs': rhs}
@@ -1,32 +1,96 @@
+import re
+from types import MappingProxyType
+
 from utility.parser import *
+from utility.errors import ModelInvalidError, ModelFrozenError
+from utility.expressionParser import parse_constraint
 
 
 class FacilityLocationModel:
     """Modello per problemi di Facility Location (UFL)"""
 
     def __init__(self, num_facilities, num_customers, fixed_costs, assignment_costs):
+        # Dopo freeze() il modello è in sola lettura e può essere condiviso tra solve concorrenti
+        self.frozen = False
         self.num_facilities = num_facilities
         self.num_customers = num_customers
         self.fixed_costs = fixed_costs
         self.assignment_costs = assignment_costs
 
+        # Attributi utente per variabili e vincoli: tag (gruppi) e dati arbitrari,
+        # indicizzati per indice di variabile/vincolo nel modello ILP
+        self.variable_tags = {}
+        self.variable_data = {}
+        self.constraint_tags = {}
+        self.constraint_data = {}
+        # Vincoli aggiuntivi definiti dall'utente (vedi add_constraint), in coda ai vincoli UFL
+        self.side_constraints = []
+
         # Validazione dei dati
         self._validate_data()
 
     def _validate_data(self):
-        """Valida la consistenza dei dati"""
+        """Valida la consistenza dei dati, raccogliendo tutti i problemi in un unico errore"""
+        details = []
         if len(self.fixed_costs) != self.num_facilities:
-            raise ValueError(f"fixed_costs ha {len(self.fixed_costs)} elementi, "
-                             f"ma num_facilities è {self.num_facilities}")
+            details.append(f"fixed_costs ha {len(self.fixed_costs)} elementi, "
+                           f"ma num_facilities è {self.num_facilities}")
 
         if len(self.assignment_costs) != self.num_customers:
-            raise ValueError(f"assignment_costs ha {len(self.assignment_costs)} righe, "
-                             f"ma num_customers è {self.num_customers}")
+            details.append(f"assignment_costs ha {len(self.assignment_costs)} righe, "
+                           f"ma num_customers è {self.num_customers}")
 
         for i, row in enumerate(self.assignment_costs):
             if len(row) != self.num_facilities:
-                raise ValueError(f"Riga {i} di assignment_costs ha {len(row)} colonne, "
-                                 f"attese {self.num_facilities}")
+                details.append(f"Riga {i} di assignment_costs ha {len(row)} colonne, "
+                               f"attese {self.num_facilities}")
+
+        if details:
+            raise ModelInvalidError("; ".join(details), details)
+
+    def __setattr__(self, name, value):
+        if getattr(self, 'frozen', False):
+            raise ModelFrozenError(f"Modello congelato: impossibile assegnare '{name}' (usare copy()).")
+        super().__setattr__(name, value)
+
+    def check_mutable(self):
+        if self.frozen:
+            raise ModelFrozenError("Modello congelato: usare copy() per ottenerne una versione modificabile.")
+
+    def freeze(self):
+        """
+        Rende il modello immutabile: costi e vincoli aggiuntivi diventano tuple, tag e dati utente
+        mapping in sola lettura, e ogni modifica successiva solleva ModelFrozenError. Un modello
+        congelato può essere risolto da più thread contemporaneamente (opzioni o scenari diversi)
+        senza copie: i solver leggono solo il modello e tengono fissaggi e soluzioni al proprio interno.
+        Restituisce il modello stesso.
+        """
+        if self.frozen:
+            return self
+        self.fixed_costs = tuple(self.fixed_costs)
+        self.assignment_costs = tuple(tuple(row) for row in self.assignment_costs)
+        self.variable_tags = MappingProxyType({i: frozenset(tags) for i, tags in self.variable_tags.items()})
+        self.variable_data = MappingProxyType(dict(self.variable_data))
+        self.constraint_tags = MappingProxyType({i: frozenset(tags) for i, tags in self.constraint_tags.items()})
+        self.constraint_data = MappingProxyType(dict(self.constraint_data))
+        self.side_constraints = tuple(
+            MappingProxyType({**constraint, 'indices': tuple(constraint['indices']),
+                              'coeffs': tuple(constraint['coeffs'])})
+            for constraint in self.side_constraints)
+        self.frozen = True
+        return self
+
+    def copy(self):
+        """Copia modificabile del modello (anche se è congelato), con tag, dati utente e vincoli aggiuntivi."""
+        model = FacilityLocationModel(self.num_facilities, self.num_customers, list(self.fixed_costs),
+                                      [list(row) for row in self.assignment_costs])
+        model.variable_tags = {i: set(tags) for i, tags in self.variable_tags.items()}
+        model.variable_data = dict(self.variable_data)
+        model.constraint_tags = {i: set(tags) for i, tags in self.constraint_tags.items()}
+        model.constraint_data = dict(self.constraint_data)
+        model.side_constraints = [{**constraint, 'indices': list(constraint['indices']),
+                                   'coeffs': list(constraint['coeffs'])} for constraint in self.side_constraints]
+        return model
 
     # Metodi getter (mantenuti per compatibilità)
     def get_num_facilities(self):
@@ -41,6 +105,125 @@ class FacilityLocationModel:
     def get_assignment_costs(self):
         return self.assignment_costs
 
+    # Layout delle variabili: x_u in [0, p), y_uv in p + u * r + v
+    def get_num_variables(self):
+        return self.num_facilities + self.num_facilities * self.num_customers
+
+    def variable_index(self, facility, customer=None):
+        """Indice della variabile x_u (se customer è None) oppure y_uv."""
+        if customer is None:
+            return facility
+        return self.num_facilities + facility * self.num_customers + customer
+
+    def variable_info(self, index):
+        """Restituisce gruppo, facility/cliente, tag e dati utente di una variabile."""
+        p, r = self.num_facilities, self.num_customers
+        if index < p:
+            info = {'name': f"x{index}", 'group': 'facility', 'facility': index, 'customer': None}
+        else:
+            u, v = divmod(index - p, r)
+            info = {'name': f"x{index}", 'group': 'assignment', 'facility': u, 'customer': v}
+        info['tags'] = set(self.variable_tags.get(index, set()))
+        info['data'] = self.variable_data.get(index)
+        return info
+
+    # Layout dei vincoli ILP: assegnamento del cliente v in [0, r), collegamento (u, v) in r + u * r + v
+    def get_num_constraints(self):
+        return self.num_customers + self.num_facilities * self.num_customers
+
+    def constraint_info(self, index):
+        """Restituisce gruppo, facility/cliente, tag e dati utente di un vincolo ILP."""
+        r = self.num_customers
+        if index < r:
+            info = {'name': f"assign_{index}", 'group': 'assignment', 'facility': None, 'customer': index}
+        else:
+            u, v = divmod(index - r, r)
+            info = {'name': f"link_{u}_{v}", 'group': 'linking', 'facility': u, 'customer': v}
+        info['tags'] = set(self.constraint_tags.get(index, set()))
+        info['data'] = self.constraint_data.get(index)
+        return info
+
+    def tag_variables(self, indices, tag):
+        self.check_mutable()
+        for i in indices:
+            self.variable_tags.setdefault(i, set()).add(tag)
+
+    def set_variable_data(self, index, data):
+        self.check_mutable()
+        self.variable_data[index] = data
+
+    def tag_constraints(self, indices, tag):
+        self.check_mutable()
+        for i in indices:
+            self.constraint_tags.setdefault(i, set()).add(tag)
+
+    def set_constraint_data(self, index, data):
+        self.check_mutable()
+        self.constraint_data[index] = data
+
+    def get_variable_group(self, group):
+        """Indici delle variabili appartenenti a un gruppo predefinito ('facility', 'assignment') o a un tag."""
+        return [i for i in range(self.get_num_variables())
+                if self.variable_info(i)['group'] == group or group in self.variable_tags.get(i, ())]
+
+    def get_constraint_group(self, group):
+        """Indici dei vincoli appartenenti a un gruppo predefinito ('assignment', 'linking') o a un tag."""
+        return [i for i in range(self.get_num_constraints())
+                if self.constraint_info(i)['group'] == group or group in self.constraint_tags.get(i, ())]
+
+    def resolve_variable(self, name):
+        """
+        Indice di una variabile dato il nome: "x{i}", "open[u]" (x_u) o "assign[u,v]" (y_uv).
+        Restituisce None se il nome non corrisponde a una variabile del modello.
+        """
+        p, r = self.num_facilities, self.num_customers
+        match = re.fullmatch(r"x(\d+)", name)
+        if match and int(match.group(1)) < self.get_num_variables():
+            return int(match.group(1))
+        match = re.fullmatch(r"open\[(\d+)\]", name)
+        if match and int(match.group(1)) < p:
+            return self.variable_index(int(match.group(1)))
+        match = re.fullmatch(r"assign\[(\d+),(\d+)\]", name)
+        if match and int(match.group(1)) < p and int(match.group(2)) < r:
+            return self.variable_index(int(match.group(1)), int(match.group(2)))
+        return None
+
+    def fixing_indices(self, fixings):
+        """Normalizza un dizionario di fissaggi (indice o nome -> valore) in {indice: valore}."""
+        normalized = {}
+        for key, value in fixings.items():
+            index = key if isinstance(key, int) else self.resolve_variable(key)
+            if index is None or not 0 <= index < self.get_num_variables():
+                raise KeyError(f"Variabile sconosciuta: {key}")
+            normalized[index] = float(value)
+        return normalized
+
+    def parse_constraint(self, text):
+        """
+        Analizza un vincolo lineare sulle variabili del modello, es. "open[0] + open[1] <= 1".
+        Le variabili UFL sono fissate dal modello: un nome sconosciuto è un ConstraintParseError.
+        """
+        return parse_constraint(text, self.resolve_variable)
+
+    def add_constraint(self, text, name=None):
+        """Aggiunge un vincolo da stringa, incluso sia nell'ILP sia nel rilassamento LP. Restituisce il vincolo."""
+        self.check_mutable()
+        constraint = self.parse_constraint(text)
+        constraint['name'] = name or f"side_{len(self.side_constraints)}"
+        constraint['text'] = text
+        self.side_constraints.append(constraint)
+        return constraint
+
+    def side_constraints_satisfied(self, values, tolerance=1e-6):
+        """Verifica i vincoli aggiuntivi su una soluzione (nome "x{i}" -> valore)."""
+        for constraint in self.side_constraints:
+            activity = sum(a * values.get(f"x{j}", 0.0) for j, a in zip(constraint['indices'], constraint['coeffs']))
+            if constraint['sense'] in ('L', 'E') and activity > constraint['rhs'] + tolerance:
+                return False
+            if constraint['sense'] in ('G', 'E') and activity < constraint['rhs'] - tolerance:
+                return False
+        return True
+
     # Metodi aggiuntivi utili
     @classmethod
     def from_dict(cls, data_dict):
@@ -53,9 +236,9 @@ class FacilityLocationModel:
         )
 
     @classmethod
-    def from_file(cls, filename):
-        """Crea un'istanza direttamente da file"""
-        data = parse_ufl_instance(filename)
+    def from_file(cls, filename, exact=False):
+        """Crea un'istanza direttamente da file (exact=True: costi come Fraction)"""
+        data = parse_ufl_instance(filename, exact=exact)
         return cls.from_dict(data)
 
     def __str__(self):
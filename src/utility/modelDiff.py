@@ -0,0 +1,129 @@
+from utility.facilityLocation import FacilityLocationModel
+
+# confronto strutturato tra due istanze UFL (es. prima/dopo una modifica dei dati)
+
+
+def _label(model: FacilityLocationModel, index):
+    """Etichetta di una variabile indipendente dal layout: open[u] oppure assign[u,v]."""
+    info = model.variable_info(index)
+    if info['group'] == 'facility':
+        return f"open[{info['facility']}]"
+    return f"assign[{info['facility']},{info['customer']}]"
+
+
+def _variables(model: FacilityLocationModel, selected):
+    """Variabili del modello (nome x{i} ed etichetta) per cui selected(facility, cliente) è vero."""
+    variables = []
+    for i in range(model.get_num_variables()):
+        info = model.variable_info(i)
+        if selected(info['facility'], info['customer']):
+            variables.append({'name': info['name'], 'label': _label(model, i)})
+    return variables
+
+
+def diff_models(a: FacilityLocationModel, b: FacilityLocationModel, tolerance=1e-9):
+    """
+    Confronta due modelli UFL, identificando facility e clienti per indice.
+    Riporta variabili e vincoli aggiunti/rimossi, i coefficienti di costo modificati e i vincoli
+    aggiuntivi aggiunti, rimossi o modificati (verso, rhs, coefficienti per etichetta di variabile).
+    Le variabili sono riportate col nome x{i} del proprio modello e con l'etichetta open[u]/assign[u,v].
+    """
+    p_a, p_b = a.get_num_facilities(), b.get_num_facilities()
+    r_a, r_b = a.get_num_customers(), b.get_num_customers()
+    p_common, r_common = min(p_a, p_b), min(r_a, r_b)
+
+    added_facilities = list(range(p_common, p_b))
+    removed_facilities = list(range(p_common, p_a))
+    added_customers = list(range(r_common, r_b))
+    removed_customers = list(range(r_common, r_a))
+
+    # Variabili aggiunte/rimosse: x_u per le facility, y_uv per le coppie che coinvolgono indici nuovi/rimossi.
+    # Il nome x{i} (come in solutionDiff e variable_info) dipende dalle dimensioni del modello, l'etichetta no
+    added_variables = _variables(b, lambda u, v: u >= p_common or (v is not None and v >= r_common))
+    removed_variables = _variables(a, lambda u, v: u >= p_common or (v is not None and v >= r_common))
+
+    # Vincoli: un assegnamento per cliente, un collegamento per coppia (u, v)
+    added_constraints = [f"assign_{v}" for v in added_customers]
+    added_constraints += [f"link_{u}_{v}" for u in range(p_b) for v in range(r_b) if u >= p_common or v >= r_common]
+    removed_constraints = [f"assign_{v}" for v in removed_customers]
+    removed_constraints += [f"link_{u}_{v}" for u in range(p_a) for v in range(r_a) if u >= p_common or v >= r_common]
+
+    modified_fixed_costs = []
+    for u in range(p_common):
+        old, new = a.get_fixed_costs()[u], b.get_fixed_costs()[u]
+        if abs(old - new) > tolerance:
+            modified_fixed_costs.append({'facility': u, 'old': old, 'new': new})
+
+    modified_assignment_costs = []
+    for v in range(r_common):
+        for u in range(p_common):
+            old, new = a.get_assignment_costs()[v][u], b.get_assignment_costs()[v][u]
+            if abs(old - new) > tolerance:
+                modified_assignment_costs.append({'facility': u, 'customer': v, 'old': old, 'new': new})
+
+    # Vincoli aggiuntivi (add_constraint), identificati per nome
+    side_a = {s['name']: s for s in a.side_constraints}
+    side_b = {s['name']: s for s in b.side_constraints}
+    added_side = [name for name in side_b if name not in side_a]
+    removed_side = [name for name in side_a if name not in side_b]
+    modified_side = []
+    for name in side_a.keys() & side_b.keys():
+        old, new = side_a[name], side_b[name]
+        changes = {}
+        if old['sense'] != new['sense']:
+            changes['sense'] = (old['sense'], new['sense'])
+        if abs(old['rhs'] - new['rhs']) > tolerance:
+            changes['rhs'] = (old['rhs'], new['rhs'])
+        # Gli indici x{i} dipendono dalle dimensioni del modello: si confronta per etichetta
+        coeffs_old = {_label(a, j): coeff for j, coeff in zip(old['indices'], old['coeffs'])}
+        coeffs_new = {_label(b, j): coeff for j, coeff in zip(new['indices'], new['coeffs'])}
+        coeffs = {}
+        for label in sorted(coeffs_old.keys() | coeffs_new.keys()):
+            before, after = coeffs_old.get(label, 0.0), coeffs_new.get(label, 0.0)
+            if abs(before - after) > tolerance:
+                coeffs[label] = (before, after)
+        if coeffs:
+            changes['coeffs'] = coeffs
+        if changes:
+            modified_side.append({'name': name, **changes})
+    modified_side.sort(key=lambda d: d['name'])
+
+    return {
+        'added_facilities': added_facilities,
+        'removed_facilities': removed_facilities,
+        'added_customers': added_customers,
+        'removed_customers': removed_customers,
+        'added_variables': added_variables,
+        'removed_variables': removed_variables,
+        'added_constraints': added_constraints,
+        'removed_constraints': removed_constraints,
+        'added_side_constraints': added_side,
+        'removed_side_constraints': removed_side,
+        'modified_side_constraints': modified_side,
+        'modified_fixed_costs': modified_fixed_costs,
+        'modified_assignment_costs': modified_assignment_costs,
+    }
+
+
+def print_model_diff(diff: dict):
+    """Stampa un riepilogo leggibile del confronto tra modelli."""
+    print("\n" + "=" * 60)
+    print("CONFRONTO TRA MODELLI")
+    print("=" * 60)
+    print(f"Facility aggiunte: {diff['added_facilities']} | rimosse: {diff['removed_facilities']}")
+    print(f"Clienti aggiunti: {diff['added_customers']} | rimossi: {diff['removed_customers']}")
+    print(f"Variabili aggiunte: {len(diff['added_variables'])} | rimosse: {len(diff['removed_variables'])}")
+    print(f"Vincoli aggiunti: {len(diff['added_constraints'])} | rimossi: {len(diff['removed_constraints'])}")
+    print(f"Vincoli aggiuntivi aggiunti: {diff['added_side_constraints']} | "
+          f"rimossi: {diff['removed_side_constraints']}")
+    for d in diff['modified_side_constraints']:
+        changes = ", ".join(f"{k} {d[k][0]} -> {d[k][1]}" for k in ('sense', 'rhs') if k in d)
+        coeffs = ", ".join(f"{label} {before:g} -> {after:g}" for label, (before, after) in d.get('coeffs', {}).items())
+        print(f"  {d['name']}: {'; '.join(part for part in (changes, coeffs) if part)}")
+    print(f"Costi fissi modificati: {len(diff['modified_fixed_costs'])}")
+    for d in diff['modified_fixed_costs']:
+        print(f"  facility {d['facility']}: {d['old']} -> {d['new']}")
+    print(f"Costi di assegnamento modificati: {len(diff['modified_assignment_costs'])}")
+    for d in diff['modified_assignment_costs']:
+        print(f"  cliente {d['customer']} -> facility {d['facility']}: {d['old']} -> {d['new']}")
+    print("=" * 60)
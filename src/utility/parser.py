@@ -1,7 +1,11 @@
 
+from fractions import Fraction
+
 # parser utilizzato per le istanze UFL (Uncapacitated Facility Location)
 
-def parse_ufl_instance(filename):
+def parse_ufl_instance(filename, exact=False):
+    """Con exact=True i costi vengono letti come Fraction (ammessi anche razionali come 3/7)."""
+    number = Fraction if exact else float
     with open(filename, 'r') as file:
         lines = [line.strip() for line in file if line.strip()]
 
@@ -16,7 +20,7 @@ def parse_ufl_instance(filename):
                 raise ValueError("File terminato prematuramente durante lettura fixed_costs")
             parts = lines[idx].split()
             try:
-                fixed_costs.append(float(parts[-1]))
+                fixed_costs.append(number(parts[-1]))
             except Exception:
                 raise ValueError(f"Errore parsing fixed_costs alla riga {idx+1}: {lines[idx]}")
             idx += 1
@@ -25,7 +29,7 @@ def parse_ufl_instance(filename):
             if idx >= len(lines):
                 raise ValueError("File terminato prematuramente durante lettura fixed_costs")
             try:
-                fixed_costs.append(float(lines[idx]))
+                fixed_costs.append(number(lines[idx]))
             except Exception as e:
                 raise ValueError(f"Errore parsing fixed_costs alla riga {idx+1}: {lines[idx]}")
             idx += 1
@@ -48,7 +52,7 @@ def parse_ufl_instance(filename):
             if idx >= len(lines):
                 raise ValueError(f"File terminato prematuramente durante costruzione riga costi cliente {len(assignment_costs)+1}")
             try:
-                row_costs.extend(map(float, lines[idx].split()))
+                row_costs.extend(map(number, lines[idx].split()))
             except Exception as e:
                 raise ValueError(f"Errore parsing righe di assignment_costs alla riga {idx+1}: {lines[idx]}")
             idx += 1
@@ -69,3 +73,14 @@ def parse_ufl_to_model(filename):
     from utility.facilityLocation import FacilityLocationModel
     return FacilityLocationModel.from_dict(data)
 
+
+
+def write_ufl_instance(model, filename):
+    """Salva un modello UFL nel formato letto da parse_ufl_instance (lo stesso delle istanze generate)."""
+    with open(filename, "w") as f:
+        f.write(f"{model.get_num_facilities()} {model.get_num_customers()}\n")
+        for cost in model.get_fixed_costs():
+            f.write(f"{cost}\n")
+        for customer_row in model.get_assignment_costs():
+            f.write(" ".join(map(str, customer_row)) + "\n")
+    return filename
@@ -0,0 +1,106 @@
+import cProfile
+import io
+import json
+import pstats
+import time
+import tracemalloc
+from contextlib import contextmanager
+from pathlib import Path
+
+# profilazione opzionale di un solve: tempo per fase, profilo CPU (cProfile) e memoria (tracemalloc)
+
+
+class SolveProfiler:
+    """
+    Raccoglie il tempo trascorso in ciascuna fase del solve (equivalente delle etichette di pprof)
+    e, se abilitati, un profilo CPU e un'istantanea dell'heap per l'intero solve.
+    Senza cpu/heap il costo è solo quello di time.perf_counter() per fase.
+    """
+    def __init__(self, cpu=False, heap=False):
+        self.cpu = cpu
+        self.heap = heap
+        self.phases = {}  # nome fase -> {'calls', 'total_time'}
+        self._profile = None
+        self._snapshot = None
+        self._started_tracemalloc = False
+
+    @contextmanager
+    def phase(self, name: str):
+        """Contesto che attribuisce il tempo trascorso alla fase indicata."""
+        start = time.perf_counter()
+        try:
+            yield
+        finally:
+            entry = self.phases.setdefault(name, {'calls': 0, 'total_time': 0.0})
+            entry['calls'] += 1
+            entry['total_time'] += time.perf_counter() - start
+
+    def start(self):
+        if self.cpu:
+            self._profile = cProfile.Profile()
+            self._profile.enable()
+        if self.heap and not tracemalloc.is_tracing():
+            tracemalloc.start()
+            self._started_tracemalloc = True
+
+    def stop(self):
+        if self._profile is not None:
+            self._profile.disable()
+        if self.heap and tracemalloc.is_tracing():
+            self._snapshot = tracemalloc.take_snapshot()
+            if self._started_tracemalloc:
+                tracemalloc.stop()
+                self._started_tracemalloc = False
+
+    def summary(self):
+        """Tempo totale e numero di chiamate per fase, ordinati per tempo decrescente."""
+        return dict(sorted(((name, {'calls': e['calls'], 'total_time': round(e['total_time'], 6)})
+                            for name, e in self.phases.items()),
+                           key=lambda item: item[1]['total_time'], reverse=True))
+
+    def dump(self, output_dir, name: str, top=30):
+        """
+        Salva i risultati in output_dir:
+        - {name}_phases.json: tempi per fase;
+        - {name}.prof: profilo CPU (leggibile con `python -m pstats` o snakeviz) e {name}_cpu.txt;
+        - {name}_heap.txt: le `top` righe di codice che allocano più memoria.
+        Restituisce la lista dei file scritti.
+        """
+        output_dir = Path(output_dir)
+        output_dir.mkdir(parents=True, exist_ok=True)
+        written = []
+
+        phases_path = output_dir / f"{name}_phases.json"
+        with open(phases_path, "w") as f:
+            json.dump(self.summary(), f, indent=2)
+        written.append(phases_path)
+
+        if self._profile is not None:
+            prof_path = output_dir / f"{name}.prof"
+            self._profile.dump_stats(str(prof_path))
+            stream = io.StringIO()
+            pstats.Stats(self._profile, stream=stream).sort_stats('cumulative').print_stats(top)
+            cpu_path = output_dir / f"{name}_cpu.txt"
+            cpu_path.write_text(stream.getvalue())
+            written += [prof_path, cpu_path]
+
+        if self._snapshot is not None:
+            heap_path = output_dir / f"{name}_heap.txt"
+            lines = [str(stat) for stat in self._snapshot.statistics('lineno')[:top]]
+            heap_path.write_text("\n".join(lines) + "\n")
+            written.append(heap_path)
+
+        return written
+
+
+class _NullProfiler:
+    """Profiler che non misura nulla: usato quando la profilazione non è richiesta."""
+    @contextmanager
+    def phase(self, name: str):
+        yield
+
+    def summary(self):
+        return {}
+
+
+NULL_PROFILER = _NullProfiler()
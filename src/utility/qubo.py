@@ -0,0 +1,133 @@
+import json
+from pathlib import Path
+
+from utility.facilityLocation import FacilityLocationModel
+
+# conversione del modello UFL in QUBO / Ising, per esperimenti con annealer e solver quantistici
+
+
+def ufl_to_qubo(model: FacilityLocationModel, penalty=None):
+    """
+    Riformula l'UFL come QUBO: min x^T Q x + offset con x binarie, spostando i vincoli nell'obiettivo:
+    - assegnamento: P * (sum_u y_uv - 1)^2 per ogni cliente v;
+    - collegamento: P * (y_uv - x_u * y_uv), che vale P solo se y_uv = 1 e x_u = 0.
+    Con penalty = P maggiore del costo di qualsiasi soluzione, l'ottimo QUBO è ottimo per l'UFL.
+    Le variabili hanno gli stessi nomi del modello ("x{i}"). Q è triangolare superiore:
+    i termini lineari stanno sulla diagonale (x_i^2 = x_i per variabili binarie).
+    """
+    p = model.get_num_facilities()
+    r = model.get_num_customers()
+    fixed_costs = model.get_fixed_costs()
+    assignment_costs = model.get_assignment_costs()
+    if penalty is None:
+        penalty = 1.0 + sum(fixed_costs) + sum(max(costs) for costs in assignment_costs)
+
+    names = [f"x{i}" for i in range(model.get_num_variables())]
+    Q = {}
+
+    def add(i, j, value):
+        key = (min(i, j), max(i, j))
+        Q[key] = Q.get(key, 0.0) + value
+
+    for u in range(p):
+        add(model.variable_index(u), model.variable_index(u), float(fixed_costs[u]))
+    offset = 0.0
+    for v in range(r):
+        indices = [model.variable_index(u, v) for u in range(p)]
+        for u, i in enumerate(indices):
+            add(i, i, float(assignment_costs[v][u]))
+            # (sum y - 1)^2 = sum y^2 + 2 sum_{i<j} y_i y_j - 2 sum y + 1, con y^2 = y
+            add(i, i, -penalty)
+            for j in indices[u + 1:]:
+                add(i, j, 2.0 * penalty)
+        offset += penalty
+        for u in range(p):
+            y, x = model.variable_index(u, v), model.variable_index(u)
+            add(y, y, penalty)
+            add(x, y, -penalty)
+
+    Q = {key: value for key, value in Q.items() if value != 0.0}
+    return {'variables': names, 'Q': Q, 'offset': offset, 'penalty': penalty}
+
+
+def qubo_energy(qubo: dict, values: dict):
+    """Valore x^T Q x + offset di un assegnamento binario (nome -> 0/1)."""
+    names = qubo['variables']
+    energy = qubo['offset']
+    for (i, j), coeff in qubo['Q'].items():
+        energy += coeff * values.get(names[i], 0.0) * values.get(names[j], 0.0)
+    return energy
+
+
+def qubo_to_ising(qubo: dict):
+    """
+    Converte il QUBO nel modello di Ising equivalente con x_i = (1 + s_i) / 2, s_i in {-1, +1}:
+    energia = sum_i h_i s_i + sum_{i<j} J_ij s_i s_j + offset.
+    """
+    h, J = {}, {}
+    offset = qubo['offset']
+    for (i, j), coeff in qubo['Q'].items():
+        if i == j:
+            h[i] = h.get(i, 0.0) + coeff / 2
+            offset += coeff / 2
+        else:
+            J[(i, j)] = J.get((i, j), 0.0) + coeff / 4
+            h[i] = h.get(i, 0.0) + coeff / 4
+            h[j] = h.get(j, 0.0) + coeff / 4
+            offset += coeff / 4
+    return {'variables': qubo['variables'], 'h': h, 'J': J, 'offset': offset}
+
+
+def ising_to_qubo(ising: dict):
+    """Conversione inversa di qubo_to_ising (s_i = 2 x_i - 1)."""
+    Q = {}
+    offset = ising['offset']
+    for i, coeff in ising['h'].items():
+        Q[(i, i)] = Q.get((i, i), 0.0) + 2 * coeff
+        offset -= coeff
+    for (i, j), coeff in ising['J'].items():
+        Q[(i, j)] = Q.get((i, j), 0.0) + 4 * coeff
+        Q[(i, i)] = Q.get((i, i), 0.0) - 2 * coeff
+        Q[(j, j)] = Q.get((j, j), 0.0) - 2 * coeff
+        offset += coeff
+    Q = {key: value for key, value in Q.items() if value != 0.0}
+    return {'variables': ising['variables'], 'Q': Q, 'offset': offset}
+
+
+def export_qubo_json(qubo: dict, output_file):
+    """
+    Salva il QUBO in JSON con termini lineari, quadratici e offset indicizzati per nome
+    (la stessa struttura linear/quadratic/offset usata dai modelli quadratici binari di dimod).
+    """
+    names = qubo['variables']
+    data = {
+        'vartype': 'BINARY',
+        'variables': names,
+        'linear': {names[i]: coeff for (i, j), coeff in qubo['Q'].items() if i == j},
+        'quadratic': [[names[i], names[j], coeff] for (i, j), coeff in qubo['Q'].items() if i != j],
+        'offset': qubo['offset'],
+        'penalty': qubo.get('penalty'),
+    }
+    output_file = Path(output_file)
+    output_file.parent.mkdir(parents=True, exist_ok=True)
+    with open(output_file, 'w') as f:
+        json.dump(data, f, indent=2)
+    return output_file
+
+
+def import_qubo_json(input_file):
+    """Legge un QUBO salvato con export_qubo_json (nomi delle variabili riportati agli indici)."""
+    with open(input_file, 'r') as f:
+        data = json.load(f)
+    if data.get('vartype', 'BINARY') != 'BINARY':
+        raise ValueError(f"Tipo di variabili non supportato: {data['vartype']} (atteso BINARY).")
+
+    names = data.get('variables') or sorted(set(data['linear']) | {n for t in data['quadratic'] for n in t[:2]})
+    position = {name: i for i, name in enumerate(names)}
+    Q = {}
+    for name, coeff in data['linear'].items():
+        Q[(position[name], position[name])] = float(coeff)
+    for a, b, coeff in data['quadratic']:
+        key = (min(position[a], position[b]), max(position[a], position[b]))
+        Q[key] = Q.get(key, 0.0) + float(coeff)
+    return {'variables': names, 'Q': Q, 'offset': float(data.get('offset', 0.0)), 'penalty': data.get('penalty')}
@@ -0,0 +1,220 @@
+import cmd
+import shlex
+from pathlib import Path
+
+from algorithm.relaxation import Relaxation
+from algorithm.solver import Solver
+from analysis.modelStats import model_stats, print_model_stats
+from utility.errors import SolverError, ModelFrozenError
+from utility.expressionParser import ConstraintParseError
+from utility.facilityLocation import FacilityLocationModel
+from utility.parser import write_ufl_instance
+
+# sessione interattiva su un modello UFL: modifica di bound e costi, risoluzione e interrogazione della soluzione
+
+
+class SolveRepl(cmd.Cmd):
+    intro = ("Sessione interattiva sul modello. Variabili: x{i}, open[u], assign[u,v]. "
+             "Digitare 'help' per l'elenco dei comandi.")
+    prompt = "(ufl) "
+
+    def __init__(self, model: FacilityLocationModel, name="model"):
+        super().__init__()
+        self.model = model
+        self.name = name
+        self.bounds = {}  # indice -> (lb, ub) modificati dall'utente
+        self.relaxation = None
+        self.last_values = None
+        self.last_kind = None
+        self._rebuild()
+
+    # --- Supporto ---
+
+    def _rebuild(self):
+        """Ricostruisce il rilassamento dopo modifiche strutturali, riapplicando i bound dell'utente."""
+        if self.relaxation is not None:
+            self.relaxation.close()
+        self.relaxation = Relaxation(self.model)
+        if self.bounds:
+            self.relaxation.change_bounds(self.bounds)
+        self.last_values, self.last_kind = None, None
+
+    def _index(self, name):
+        index = self.model.resolve_variable(name)
+        if index is None:
+            raise ValueError(f"variabile sconosciuta '{name}'")
+        return index
+
+    def _args(self, line, count):
+        args = shlex.split(line)
+        if len(args) != count:
+            raise ValueError(f"attesi {count} argomenti, trovati {len(args)}")
+        return args
+
+    def onecmd(self, line):
+        # Gli errori di input non devono chiudere la sessione
+        try:
+            return super().onecmd(line)
+        except (ValueError, KeyError, IndexError, ConstraintParseError, ModelFrozenError) as e:
+            print(f"Errore: {e}")
+        except SolverError as e:
+            print(f"Errore del solver ({e.status.value}): {e}")
+        return False
+
+    def emptyline(self):
+        return False
+
+    # --- Modifiche al modello ---
+
+    def do_bounds(self, line):
+        """bounds VAR LB UB: imposta i bound di una variabile (es. bounds open[2] 0 0)."""
+        name, lb, ub = self._args(line, 3)
+        index, lb, ub = self._index(name), float(lb), float(ub)
+        if lb > ub:
+            raise ValueError(f"lb ({lb}) maggiore di ub ({ub})")
+        self.bounds[index] = (lb, ub)
+        self.relaxation.change_bounds({index: (lb, ub)})
+        print(f"{name}: bound [{lb:g}, {ub:g}]")
+
+    def do_fix(self, line):
+        """fix VAR VALORE: fissa una variabile (equivale a bounds VAR VALORE VALORE)."""
+        name, value = self._args(line, 2)
+        self.do_bounds(f"{name} {value} {value}")
+
+    def do_unfix(self, line):
+        """unfix VAR: ripristina i bound originali [0, 1] di una variabile."""
+        name, = self._args(line, 1)
+        index = self._index(name)
+        self.bounds.pop(index, None)
+        self.relaxation.change_bounds({index: (0.0, 1.0)})
+        print(f"{name}: bound [0, 1]")
+
+    def do_obj(self, line):
+        """obj VAR COSTO: cambia il coefficiente di costo (costo fisso per open[u], di assegnamento per assign[u,v])."""
+        name, cost = self._args(line, 2)
+        index, cost = self._index(name), float(cost)
+        self.model.check_mutable()
+        info = self.model.variable_info(index)
+        if info['group'] == 'facility':
+            self.model.fixed_costs[info['facility']] = cost
+        else:
+            self.model.assignment_costs[info['customer']][info['facility']] = cost
+        self.relaxation.lp.objective.set_linear(index, cost)
+        print(f"{name}: costo {cost:g}")
+
+    def do_constraint(self, line):
+        """constraint ESPRESSIONE: aggiunge un vincolo lineare (es. constraint open[0] + open[1] <= 1)."""
+        constraint = self.model.add_constraint(line.strip())
+        self._rebuild()
+        print(f"Aggiunto il vincolo {constraint['name']}: {constraint['text']}")
+
+    # --- Risoluzione ---
+
+    def do_lp(self, line):
+        """lp: risolve il rilassamento LP con i bound correnti (simplesso duale dalla base precedente)."""
+        value, status = self.relaxation.resolve()
+        self.last_values = self.relaxation.values() if status.has_solution else None
+        self.last_kind = 'lp'
+        if value is None:
+            print(f"Stato: {status.value}")
+        else:
+            print(f"Stato: {status.value} | Obiettivo LP: {value:.6f} | Iterazioni: {self.relaxation.iterations()}")
+
+    def do_ilp(self, line):
+        """ilp: risolve l'ILP; i bound che escludono 0 o 1 fissano la variabile binaria."""
+        fixings = {}
+        for index, (lb, ub) in self.bounds.items():
+            if lb > 0:
+                fixings[index] = 1.0
+            elif ub < 1:
+                fixings[index] = 0.0
+        solver = Solver(self.model)
+        # Senza soluzione determine_optimal solleva SolverError, riportato da onecmd con lo stato
+        self.last_values, self.last_kind = None, None
+        with solver.fixing(fixings):
+            value = solver.determine_optimal(Path(self.name))
+        self.last_values = [solver.optimal_values[f"x{i}"] for i in range(self.model.get_num_variables())]
+        self.last_kind = 'ilp'
+        print(f"Obiettivo ILP: {value:.6f}")
+
+    def do_soft(self, line):
+        """soft [SELEZIONE...]: ILP elastico, minimizza la violazione dei vincoli indicati (gruppi, tag o nomi; default tutti)."""
+        solver = Solver(self.model)
+        result = solver.soft_solve(Path(self.name), soft=shlex.split(line) or None)
+        self.last_values = [solver.optimal_values[f"x{i}"] for i in range(self.model.get_num_variables())]
+        self.last_kind = 'ilp'
+        for violation in result['violations']:
+            print(f"  {violation['name']:<16} {violation['kind']:<8} {violation['violation']:.6g} "
+                  f"(peso {violation['weight']:g})")
+
+    # --- Interrogazione ---
+
+    def _require_solution(self):
+        if self.last_values is None:
+            raise ValueError("nessuna soluzione disponibile: eseguire prima 'lp' o 'ilp'")
+
+    def do_value(self, line):
+        """value VAR: valore di una variabile nell'ultima soluzione."""
+        self._require_solution()
+        name, = self._args(line, 1)
+        print(f"{name} = {self.last_values[self._index(name)]:.6g} ({self.last_kind})")
+
+    def do_values(self, line):
+        """values: elenca le variabili non nulle dell'ultima soluzione."""
+        self._require_solution()
+        for index, value in enumerate(self.last_values):
+            if abs(value) > 1e-9:
+                info = self.model.variable_info(index)
+                label = (f"open[{info['facility']}]" if info['group'] == 'facility'
+                         else f"assign[{info['facility']},{info['customer']}]")
+                print(f"  {info['name']:<8} {label:<16} {value:.6g}")
+
+    def do_dual(self, line):
+        """dual RIGA: valore duale di una riga del rilassamento (indice o nome c{i})."""
+        if self.last_kind != 'lp':
+            raise ValueError("i duali sono disponibili solo dopo 'lp'")
+        row, = self._args(line, 1)
+        index = int(row[1:]) if row.startswith('c') else int(row)
+        print(f"c{index}: duale {self.relaxation.duals()[index]:.6g}")
+
+    def do_duals(self, line):
+        """duals: elenca i valori duali non nulli del rilassamento."""
+        if self.last_kind != 'lp':
+            raise ValueError("i duali sono disponibili solo dopo 'lp'")
+        for index, dual in enumerate(self.relaxation.duals()):
+            if abs(dual) > 1e-9:
+                print(f"  c{index:<6} {dual:.6g}")
+
+    def do_reduced(self, line):
+        """reduced VAR: costo ridotto di una variabile nel rilassamento."""
+        if self.last_kind != 'lp':
+            raise ValueError("i costi ridotti sono disponibili solo dopo 'lp'")
+        name, = self._args(line, 1)
+        print(f"{name}: costo ridotto {self.relaxation.reduced_costs()[self._index(name)]:.6g}")
+
+    def do_stats(self, line):
+        """stats: statistiche della matrice del modello corrente."""
+        print_model_stats(model_stats(self.model))
+
+    def do_save(self, line):
+        """save FILE: salva il modello (con i costi modificati) nel formato delle istanze UFL."""
+        path, = self._args(line, 1)
+        write_ufl_instance(self.model, path)
+        if self.model.side_constraints or self.bounds:
+            print("Nota: vincoli aggiuntivi e bound non fanno parte del formato UFL e non vengono salvati.")
+        print(f"Modello salvato in {path}")
+
+    def do_quit(self, line):
+        """quit: termina la sessione."""
+        self.relaxation.close()
+        return True
+
+    do_exit = do_quit
+    do_EOF = do_quit
+
+
+def run_repl(instance_path):
+    """Avvia la sessione interattiva su un file di istanza UFL."""
+    instance_path = Path(instance_path)
+    model = FacilityLocationModel.from_file(instance_path)
+    SolveRepl(model, name=instance_path.stem).cmdloop()
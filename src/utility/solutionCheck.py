@@ -0,0 +1,89 @@
+from utility.facilityLocation import FacilityLocationModel
+from utility.solutionDiff import constraint_activities
+
+# verifica di una soluzione UFL vincolo per vincolo, per diagnosticare problemi di tolleranza
+
+
+def _constraint_bounds(info):
+    """Verso e rhs dei vincoli ILP: assign_v è sum_u y_uv = 1, link_u_v è y_uv - x_u <= 0."""
+    return ('E', 1.0) if info['group'] == 'assignment' else ('L', 0.0)
+
+
+def _violation(activity, sense, rhs):
+    if sense == 'E':
+        return abs(activity - rhs)
+    return max(0.0, activity - rhs) if sense == 'L' else max(0.0, rhs - activity)
+
+
+def check_solution(model: FacilityLocationModel, values: dict, tolerance=1e-6, relaxed_groups=()):
+    """
+    Calcola per ogni vincolo (UFL e aggiunti con add_constraint) l'attività e l'entità della
+    violazione (0 se rispettato), e per ogni variabile le violazioni dei bound [0, 1] e
+    dell'integralità; le variabili dei relaxed_groups, risolte come continue, non sono
+    soggette al controllo di integralità.
+    Restituisce un report con tutti i valori e l'elenco di quelli oltre la tolleranza.
+    """
+    activities = constraint_activities(model, values)
+
+    constraints = []
+    for i in range(model.get_num_constraints()):
+        info = model.constraint_info(i)
+        sense, rhs = _constraint_bounds(info)
+        activity = activities[info['name']]
+        violation = _violation(activity, sense, rhs)
+        constraints.append({'name': info['name'], 'group': info['group'], 'sense': sense, 'rhs': rhs,
+                            'activity': activity, 'violation': violation, 'violated': violation > tolerance})
+    for constraint in model.side_constraints:
+        activity = sum(float(a) * values.get(f"x{j}", 0.0) for j, a in zip(constraint['indices'], constraint['coeffs']))
+        sense, rhs = constraint['sense'], float(constraint['rhs'])
+        violation = _violation(activity, sense, rhs)
+        constraints.append({'name': constraint['name'], 'group': 'side', 'sense': sense, 'rhs': rhs,
+                            'activity': activity, 'violation': violation, 'violated': violation > tolerance})
+
+    continuous = {i for group in relaxed_groups for i in model.get_variable_group(group)}
+    variables = []
+    for i in range(model.get_num_variables()):
+        name = model.variable_info(i)['name']
+        value = values.get(name, 0.0)
+        bound_violation = max(0.0, -value, value - 1.0)
+        integrality_violation = 0.0 if i in continuous else abs(value - round(value))
+        variables.append({'name': name, 'value': value, 'bound_violation': bound_violation,
+                          'integrality_violation': integrality_violation,
+                          'violated': bound_violation > tolerance or integrality_violation > tolerance})
+
+    violated_constraints = [c for c in constraints if c['violated']]
+    violated_variables = [v for v in variables if v['violated']]
+    return {
+        'tolerance': tolerance,
+        'feasible': not violated_constraints and not violated_variables,
+        'max_constraint_violation': max((c['violation'] for c in constraints), default=0.0),
+        'max_bound_violation': max((v['bound_violation'] for v in variables), default=0.0),
+        'max_integrality_violation': max((v['integrality_violation'] for v in variables), default=0.0),
+        'constraints': constraints,
+        'variables': variables,
+        'violated_constraints': violated_constraints,
+        'violated_variables': violated_variables,
+    }
+
+
+def print_violation_report(report: dict, max_rows=20):
+    """Stampa le violazioni massime e i vincoli/variabili oltre la tolleranza (i peggiori per primi)."""
+    print("\n" + "=" * 60)
+    print(f"VERIFICA DELLA SOLUZIONE (tolleranza {report['tolerance']:g})")
+    print("=" * 60)
+    print(f"Violazione massima: vincoli {report['max_constraint_violation']:.3e} | "
+          f"bound {report['max_bound_violation']:.3e} | integralità {report['max_integrality_violation']:.3e}")
+    if report['feasible']:
+        print("Nessun vincolo violato oltre la tolleranza.")
+    else:
+        worst = sorted(report['violated_constraints'], key=lambda c: c['violation'], reverse=True)
+        print(f"Vincoli violati: {len(worst)}")
+        for c in worst[:max_rows]:
+            op = {'E': '=', 'L': '<=', 'G': '>='}[c['sense']]
+            print(f"  {c['name']:<14} attività {c['activity']:.9g} {op} {c['rhs']:g}  (violazione {c['violation']:.3e})")
+        worst = sorted(report['violated_variables'],
+                       key=lambda v: max(v['bound_violation'], v['integrality_violation']), reverse=True)
+        print(f"Variabili violate: {len(worst)}")
+        for v in worst[:max_rows]:
+            print(f"  {v['name']:<8} valore {v['value']:.9g}")
+    print("=" * 60)
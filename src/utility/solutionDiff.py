@@ -0,0 +1,108 @@
+from utility.facilityLocation import FacilityLocationModel
+
+# confronto strutturato tra due soluzioni dello stesso modello UFL
+
+
+def read_solution_sol(filename):
+    """Legge un file .sol in formato MIPLIB e restituisce (obiettivo, valori)."""
+    objective, values = None, {}
+    with open(filename, 'r') as f:
+        for line in f:
+            parts = line.split()
+            if len(parts) < 2 or line.startswith('#'):
+                continue
+            if parts[0] == "=obj=":
+                objective = float(parts[1])
+            else:
+                values[parts[0]] = float(parts[1])
+    return objective, values
+
+
+def read_solution_instance(filename):
+    """Nome dell'istanza scritto nell'intestazione del file .sol ('# instance <nome>'), None se assente."""
+    with open(filename, 'r') as f:
+        for line in f:
+            if not line.startswith('#'):
+                break
+            parts = line[1:].split()
+            if len(parts) == 2 and parts[0] == 'instance':
+                return parts[1]
+    return None
+
+
+def solution_cost(model: FacilityLocationModel, values: dict):
+    """Calcola il costo UFL (fissi + assegnamento) di una soluzione."""
+    cost = 0.0
+    for i in range(model.get_num_variables()):
+        info = model.variable_info(i)
+        value = values.get(info['name'], 0.0)
+        if info['group'] == 'facility':
+            cost += model.get_fixed_costs()[info['facility']] * value
+        else:
+            cost += model.get_assignment_costs()[info['customer']][info['facility']] * value
+    return cost
+
+
+def constraint_activities(model: FacilityLocationModel, values: dict):
+    """Attività (lato sinistro) dei vincoli ILP: assign_v = sum_u y_uv, link_u_v = y_uv - x_u."""
+    def val(index):
+        return values.get(f"x{index}", 0.0)
+
+    activities = {}
+    for i in range(model.get_num_constraints()):
+        info = model.constraint_info(i)
+        u, v = info['facility'], info['customer']
+        if info['group'] == 'assignment':
+            activities[info['name']] = sum(val(model.variable_index(k, v)) for k in range(model.get_num_facilities()))
+        else:
+            activities[info['name']] = val(model.variable_index(u, v)) - val(model.variable_index(u))
+    return activities
+
+
+def diff_solutions(a: dict, b: dict, model: FacilityLocationModel, tolerance=1e-6):
+    """
+    Confronta due soluzioni (dizionari nome -> valore) dello stesso modello.
+    Restituisce le variabili cambiate, la differenza di obiettivo e i vincoli la cui attività è cambiata.
+    """
+    changed_variables = []
+    for i in range(model.get_num_variables()):
+        info = model.variable_info(i)
+        old, new = a.get(info['name'], 0.0), b.get(info['name'], 0.0)
+        if abs(old - new) > tolerance:
+            changed_variables.append({**info, 'old': old, 'new': new})
+
+    old_activities = constraint_activities(model, a)
+    new_activities = constraint_activities(model, b)
+    moved_constraints = [
+        {'name': name, 'old': old_activities[name], 'new': new_activities[name]}
+        for name in old_activities
+        if abs(old_activities[name] - new_activities[name]) > tolerance
+    ]
+
+    old_cost, new_cost = solution_cost(model, a), solution_cost(model, b)
+    return {
+        'objective_old': old_cost,
+        'objective_new': new_cost,
+        'objective_delta': new_cost - old_cost,
+        'opened_facilities': [v['facility'] for v in changed_variables if v['group'] == 'facility' and v['new'] > v['old']],
+        'closed_facilities': [v['facility'] for v in changed_variables if v['group'] == 'facility' and v['new'] < v['old']],
+        'changed_variables': changed_variables,
+        'moved_constraints': moved_constraints,
+    }
+
+
+def print_solution_diff(diff: dict):
+    """Stampa un riepilogo leggibile del confronto tra soluzioni."""
+    print("\n" + "=" * 60)
+    print("CONFRONTO TRA SOLUZIONI")
+    print("=" * 60)
+    print(f"Obiettivo: {diff['objective_old']:.4f} -> {diff['objective_new']:.4f} "
+          f"(delta {diff['objective_delta']:+.4f})")
+    print(f"Facility aperte: {diff['opened_facilities']}")
+    print(f"Facility chiuse: {diff['closed_facilities']}")
+    print(f"Variabili cambiate: {len(diff['changed_variables'])}")
+    for v in diff['changed_variables']:
+        target = f"facility {v['facility']}" if v['group'] == 'facility' else f"cliente {v['customer']} -> facility {v['facility']}"
+        print(f"  {v['name']:<8} ({target}): {v['old']:.4g} -> {v['new']:.4g}")
+    print(f"Vincoli con attività cambiata: {len(diff['moved_constraints'])}")
+    print("=" * 60)
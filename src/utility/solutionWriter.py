@@ -0,0 +1,57 @@
+import csv
+from datetime import datetime
+from pathlib import Path
+
+# writer per esportare le soluzioni ILP in formati leggibili da strumenti esterni
+
+
+def write_solution_sol(filename, objective_value, values: dict, instance_name=None):
+    """
+    Scrive la soluzione nel formato MIPLIB (.sol):
+    una prima riga con il valore obiettivo, poi una riga 'nome valore' per variabile.
+    Se instance_name è indicato viene scritto come commento iniziale, per ritrovare il modello.
+    """
+    path = Path(filename)
+    path.parent.mkdir(parents=True, exist_ok=True)
+
+    with open(path, "w") as f:
+        if instance_name is not None:
+            f.write(f"# instance {instance_name}\n")
+        f.write(f"=obj= {objective_value:.10g}\n")
+        for name, value in values.items():
+            # Il formato MIPLIB omette spesso le variabili nulle, ma le scriviamo tutte per chiarezza
+            f.write(f"{name} {value:.10g}\n")
+
+    print(f"Soluzione (.sol) salvata in: {path}")
+    return path
+
+
+def write_solution_csv(filename, values: dict):
+    """Scrive la soluzione come CSV semplice con intestazione 'name,value'."""
+    path = Path(filename)
+    path.parent.mkdir(parents=True, exist_ok=True)
+
+    with open(path, "w", newline="") as f:
+        writer = csv.writer(f)
+        writer.writerow(["name", "value"])
+        for name, value in values.items():
+            writer.writerow([name, f"{value:.10g}"])
+
+    print(f"Soluzione (.csv) salvata in: {path}")
+    return path
+
+
+def write_solution(output_dir: Path, instance_name: str, objective_value, values: dict, label=None):
+    """
+    Scrive la soluzione in entrambi i formati (.sol e .csv) nella cartella indicata.
+    I file si chiamano {instance_name}[_{label}]_{data_ora}, così una nuova risoluzione
+    della stessa istanza non sovrascrive le precedenti e le due soluzioni si possono confrontare.
+    """
+    if values is None or objective_value is None:
+        print(f"Nessuna soluzione da salvare per {instance_name}.")
+        return None
+
+    stem = "_".join(part for part in (instance_name, label, datetime.now().strftime("%Y%m%d_%H%M%S")) if part)
+    sol_path = write_solution_sol(Path(output_dir) / f"{stem}.sol", objective_value, values, instance_name)
+    write_solution_csv(Path(output_dir) / f"{stem}.csv", values)
+    return sol_path
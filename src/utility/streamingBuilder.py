@@ -0,0 +1,127 @@
+import json
+from array import array
+from pathlib import Path
+
+import cplex
+
+# costruzione incrementale di modelli molto grandi: le righe vengono scritte su disco in formato CSR
+# (indptr, indices, data, rhs, senses) invece di restare in memoria come matrice densa
+
+
+class StreamingModelBuilder:
+    """
+    Scrive i vincoli uno alla volta in file binari colonnari nella cartella indicata.
+    In memoria resta solo il buffer corrente (al più chunk_size coefficienti), quindi
+    la memoria non dipende dal numero totale di non zeri.
+    Uso:
+
+        with StreamingModelBuilder(path, n_cols) as builder:
+            builder.add_row([0, 3], [1.0, -1.0], rhs=0.0)
+        reader = StreamingModelReader(path)
+        reader.add_to_cplex(prob)
+    """
+    FILES = ('indptr', 'indices', 'data', 'rhs', 'senses')
+
+    def __init__(self, directory, n_cols, chunk_size=1_000_000):
+        self.directory = Path(directory)
+        self.directory.mkdir(parents=True, exist_ok=True)
+        self.n_cols = n_cols
+        self.chunk_size = chunk_size
+        self.n_rows = 0
+        self.nnz = 0
+        self._files = {name: open(self.directory / f"{name}.bin", 'wb') for name in self.FILES}
+        self._buffers = self._new_buffers()
+        self._buffers['indptr'].append(0)
+
+    @staticmethod
+    def _new_buffers():
+        return {'indptr': array('q'), 'indices': array('i'), 'data': array('d'),
+                'rhs': array('d'), 'senses': array('b')}
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    def add_row(self, indices, values, rhs, sense='L'):
+        """Aggiunge il vincolo sum_k values[k] * x[indices[k]] (sense) rhs; i coefficienti nulli vengono scartati."""
+        if sense not in ('L', 'G', 'E'):
+            raise ValueError(f"Verso del vincolo non valido: {sense}")
+        for j, a in zip(indices, values):
+            if not 0 <= j < self.n_cols:
+                raise IndexError(f"Colonna {j} fuori dal modello ({self.n_cols} variabili).")
+            if a != 0.0:
+                self._buffers['indices'].append(int(j))
+                self._buffers['data'].append(float(a))
+                self.nnz += 1
+        self._buffers['indptr'].append(self.nnz)
+        self._buffers['rhs'].append(float(rhs))
+        self._buffers['senses'].append(ord(sense))
+        self.n_rows += 1
+        if len(self._buffers['data']) >= self.chunk_size:
+            self.flush()
+
+    def flush(self):
+        for name, buffer in self._buffers.items():
+            buffer.tofile(self._files[name])
+        self._buffers = self._new_buffers()
+
+    def close(self):
+        if self._files is None:
+            return
+        self.flush()
+        for f in self._files.values():
+            f.close()
+        self._files = None
+        with open(self.directory / "meta.json", 'w') as f:
+            json.dump({'n_rows': self.n_rows, 'n_cols': self.n_cols, 'nnz': self.nnz}, f)
+
+
+class StreamingModelReader:
+    """Rilegge a blocchi le righe scritte da StreamingModelBuilder."""
+    def __init__(self, directory):
+        self.directory = Path(directory)
+        with open(self.directory / "meta.json", 'r') as f:
+            meta = json.load(f)
+        self.n_rows, self.n_cols, self.nnz = meta['n_rows'], meta['n_cols'], meta['nnz']
+
+    def _read(self, name, typecode, start, count):
+        values = array(typecode)
+        with open(self.directory / f"{name}.bin", 'rb') as f:
+            f.seek(start * values.itemsize)
+            values.fromfile(f, count)
+        return values
+
+    def iter_rows(self, chunk_rows=10_000):
+        """Genera (indici, valori, rhs, verso) riga per riga, leggendo chunk_rows righe per volta."""
+        for first in range(0, self.n_rows, chunk_rows):
+            count = min(chunk_rows, self.n_rows - first)
+            indptr = self._read('indptr', 'q', first, count + 1)
+            indices = self._read('indices', 'i', indptr[0], indptr[-1] - indptr[0])
+            data = self._read('data', 'd', indptr[0], indptr[-1] - indptr[0])
+            rhs = self._read('rhs', 'd', first, count)
+            senses = self._read('senses', 'b', first, count)
+            for k in range(count):
+                lo, hi = indptr[k] - indptr[0], indptr[k + 1] - indptr[0]
+                yield indices[lo:hi].tolist(), data[lo:hi].tolist(), rhs[k], chr(senses[k])
+
+    def add_to_cplex(self, prob: cplex.Cplex, chunk_rows=10_000, name_prefix='c'):
+        """Aggiunge a prob tutti i vincoli, un blocco alla volta (nomi {name_prefix}{i})."""
+        batch, row_index = [], 0
+        for row in self.iter_rows(chunk_rows):
+            batch.append(row)
+            if len(batch) >= chunk_rows:
+                self._add_batch(prob, batch, row_index, name_prefix)
+                row_index += len(batch)
+                batch = []
+        if batch:
+            self._add_batch(prob, batch, row_index, name_prefix)
+
+    @staticmethod
+    def _add_batch(prob, batch, first, name_prefix):
+        prob.linear_constraints.add(
+            lin_expr=[cplex.SparsePair(ind=ind, val=val) for ind, val, _, _ in batch],
+            rhs=[rhs for _, _, rhs, _ in batch], senses=[sense for _, _, _, sense in batch],
+            names=[f"{name_prefix}{first + k}" for k in range(len(batch))]
+        )